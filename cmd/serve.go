@@ -1,42 +1,65 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/brpaz/prom-dirsize-exporter/internal/collector"
+	"github.com/brpaz/prom-dirsize-exporter/internal/config"
+	"github.com/brpaz/prom-dirsize-exporter/internal/notifier"
 	"github.com/brpaz/prom-dirsize-exporter/internal/server"
+	"github.com/brpaz/prom-dirsize-exporter/internal/walker"
 
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
 
 const (
+	serveFlagConfig        = "config"
 	serveFlagMetricsPort   = "metrics-port"
 	serveFlagMetricsPath   = "metrics-path"
+	serveFlagMetricNS      = "metric-namespace"
 	serviceFlagDirectories = "directories"
+	serveFlagOneFileSystem = "one-file-system"
+	serveFlagScanInterval  = "scan-interval"
+	serveFlagWebConfigFile = "web.config.file"
+	serveFlagListenAddress = "web.listen-address"
+	serveFlagSystemdSocket = "web.systemd-socket"
 )
 
+// flagEnvVars maps each flag of the serve command to the environment variable that can be used to set it
+var flagEnvVars = []struct {
+	flag   string
+	envVar string
+}{
+	{serveFlagConfig, "CONFIG_FILE"},
+	{serveFlagMetricsPort, "METRICS_PORT"},
+	{serveFlagMetricsPath, "METRICS_PATH"},
+	{serveFlagMetricNS, "METRIC_NAMESPACE"},
+	{serviceFlagDirectories, "DIRECTORIES"},
+	{serveFlagOneFileSystem, "ONE_FILE_SYSTEM"},
+	{serveFlagScanInterval, "SCAN_INTERVAL"},
+	{serveFlagWebConfigFile, "WEB_CONFIG_FILE"},
+	{serveFlagListenAddress, "WEB_LISTEN_ADDRESS"},
+	{serveFlagSystemdSocket, "WEB_SYSTEMD_SOCKET"},
+}
+
 // SetFlagsFromEnv sets the command flags from environment variables.
 // The environment variables take precedence over any defined flag.
 func SetFlagsFromEnv(cmd *cobra.Command) error {
-	if !cmd.Flags().Changed(serveFlagMetricsPort) && os.Getenv("METRICS_PORT") != "" {
-		if err := cmd.Flags().Set(serveFlagMetricsPort, os.Getenv("METRICS_PORT")); err != nil {
-			return err
-		}
-	}
-
-	if !cmd.Flags().Changed(serveFlagMetricsPath) && os.Getenv("METRICS_PATH") != "" {
-		if err := cmd.Flags().Set(serveFlagMetricsPath, os.Getenv("METRICS_PATH")); err != nil {
-			return err
+	for _, f := range flagEnvVars {
+		if cmd.Flags().Changed(f.flag) || os.Getenv(f.envVar) == "" {
+			continue
 		}
-	}
 
-	if !cmd.Flags().Changed("directories") && os.Getenv("DIRECTORIES") != "" {
-		if err := cmd.Flags().Set(serviceFlagDirectories, os.Getenv("DIRECTORIES")); err != nil {
+		if err := cmd.Flags().Set(f.flag, os.Getenv(f.envVar)); err != nil {
 			return err
 		}
 	}
@@ -47,9 +70,10 @@ func SetFlagsFromEnv(cmd *cobra.Command) error {
 // NewServeCmd returns a new instance of the serve command that will start the metrics http server
 func NewServeCmd(logger *zap.Logger) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "serve",
-		Short:   "Starts the prometheus exporter",
-		Example: `prom-dirsize-exporter serve --metricsPort 8080 --metricsPath /metrics --directories /var/log:/var/tmp`,
+		Use:   "serve",
+		Short: "Starts the prometheus exporter",
+		Example: `prom-dirsize-exporter serve --metricsPort 8080 --metricsPath /metrics --directories /var/log:/var/tmp
+prom-dirsize-exporter serve --config exporter.yaml`,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if err := SetFlagsFromEnv(cmd); err != nil {
 				return fmt.Errorf("error setting flags from environment variables: %w", err)
@@ -58,49 +82,205 @@ func NewServeCmd(logger *zap.Logger) *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			dirsList, err := cmd.Flags().GetString(serviceFlagDirectories)
-			if err != nil {
-				return fmt.Errorf("error reading directories flag: %w", err)
-			}
-
-			metricsPath, err := cmd.Flags().GetString(serveFlagMetricsPath)
+			cfg, err := loadConfig(cmd)
 			if err != nil {
-				return fmt.Errorf("error reading metricsPath flag: %w", err)
+				return err
 			}
 
-			metricsPort, err := cmd.Flags().GetInt(serveFlagMetricsPort)
-			if err != nil {
-				return fmt.Errorf("error reading metricsPort flag: %w", err)
-			}
-
-			directoriesToMonitor := make([]string, 0)
-			directoriesToMonitor = append(directoriesToMonitor, filepath.SplitList(dirsList)...)
-			return runServer(logger, directoriesToMonitor, metricsPort, metricsPath)
+			return runServer(cmd, logger, cfg)
 		},
 	}
 
+	cmd.PersistentFlags().StringP(serveFlagConfig, "c", "", "path to a YAML or TOML configuration file")
 	cmd.PersistentFlags().IntP("metrics-port", "p", server.DefaultMetricsPort, "the port where the metrics server will listen")
 	cmd.PersistentFlags().StringP("metrics-path", "m", server.DefaultMetricsPath, "the path where the metrics will be exposed")
+	cmd.PersistentFlags().String(serveFlagMetricNS, collector.CollectorNamespace, "the namespace of the metric names, used to avoid collisions between exporters")
 	cmd.PersistentFlags().StringP("directories", "d", "", "a colon separated list of directories to monitor")
+	cmd.PersistentFlags().Bool(serveFlagOneFileSystem, false, "skip directories that are on a different filesystem than the monitored directory")
+	cmd.PersistentFlags().Duration(serveFlagScanInterval, collector.DefaultScanInterval, "the interval between scans of each directory")
+	cmd.PersistentFlags().String(serveFlagWebConfigFile, "", "path to a web config file, in the exporter toolkit format, to enable TLS and basic auth")
+	cmd.PersistentFlags().StringSlice(serveFlagListenAddress, nil, "an address to listen on, like \"127.0.0.1:8080\", \"[::1]:8080\" or \"unix:/run/exporter.sock\". Can be repeated. Defaults to all the interfaces on the metrics port")
+	cmd.PersistentFlags().Bool(serveFlagSystemdSocket, false, "listen on the sockets passed by systemd socket activation, instead of the listen addresses")
 
 	return cmd
 }
 
-func runServer(logger *zap.Logger, directoriesToMonitor []string, metricsPort int, metricsPath string) error {
-	// Initialize and register collector
-	dirsizeCollector := collector.NewDirectoryCollector(
+// loadConfig builds the exporter configuration from the config file, if any, and the command flags.
+// Flags that were explicitly set, either directly or through environment variables, override the config file values.
+func loadConfig(cmd *cobra.Command) (*config.Config, error) {
+	configFile, err := cmd.Flags().GetString(serveFlagConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config flag: %w", err)
+	}
+
+	cfg := config.Default()
+	if configFile != "" {
+		if cfg, err = config.Load(configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyFlagOverrides(cmd, cfg); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	cfg.SetDefaultNames()
+
+	return cfg, nil
+}
+
+// applyFlagOverrides sets the config values of the flags that were explicitly set
+func applyFlagOverrides(cmd *cobra.Command, cfg *config.Config) error {
+	flags := cmd.Flags()
+	var err error
+
+	if flags.Changed(serviceFlagDirectories) {
+		dirsList, err := flags.GetString(serviceFlagDirectories)
+		if err != nil {
+			return fmt.Errorf("error reading directories flag: %w", err)
+		}
+
+		cfg.Directories = make([]config.Directory, 0)
+		for _, dir := range filepath.SplitList(dirsList) {
+			cfg.Directories = append(cfg.Directories, config.Directory{Path: dir})
+		}
+	}
+
+	if flags.Changed(serveFlagMetricsPath) {
+		if cfg.MetricsPath, err = flags.GetString(serveFlagMetricsPath); err != nil {
+			return fmt.Errorf("error reading metricsPath flag: %w", err)
+		}
+	}
+
+	if flags.Changed(serveFlagMetricNS) {
+		if cfg.MetricNamespace, err = flags.GetString(serveFlagMetricNS); err != nil {
+			return fmt.Errorf("error reading metric-namespace flag: %w", err)
+		}
+	}
+
+	if flags.Changed(serveFlagMetricsPort) {
+		if cfg.MetricsPort, err = flags.GetInt(serveFlagMetricsPort); err != nil {
+			return fmt.Errorf("error reading metricsPort flag: %w", err)
+		}
+	}
+
+	if flags.Changed(serveFlagOneFileSystem) {
+		if cfg.OneFileSystem, err = flags.GetBool(serveFlagOneFileSystem); err != nil {
+			return fmt.Errorf("error reading one-file-system flag: %w", err)
+		}
+	}
+
+	if flags.Changed(serveFlagScanInterval) {
+		scanInterval, err := flags.GetDuration(serveFlagScanInterval)
+		if err != nil {
+			return fmt.Errorf("error reading scan-interval flag: %w", err)
+		}
+		cfg.ScanInterval = config.Duration(scanInterval)
+	}
+
+	return nil
+}
+
+func runServer(cmd *cobra.Command, logger *zap.Logger, cfg *config.Config) error {
+	webConfigFile, err := cmd.Flags().GetString(serveFlagWebConfigFile)
+	if err != nil {
+		return fmt.Errorf("error reading web.config.file flag: %w", err)
+	}
+
+	listenAddresses, err := cmd.Flags().GetStringSlice(serveFlagListenAddress)
+	if err != nil {
+		return fmt.Errorf("error reading web.listen-address flag: %w", err)
+	}
+
+	if len(listenAddresses) == 0 {
+		listenAddresses = []string{fmt.Sprintf(":%d", cfg.MetricsPort)}
+	}
+
+	systemdSocket, err := cmd.Flags().GetBool(serveFlagSystemdSocket)
+	if err != nil {
+		return fmt.Errorf("error reading web.systemd-socket flag: %w", err)
+	}
+
+	collectorOpts := []collector.DirectoryCollectorOption{
 		collector.WithLogger(logger),
-		collector.WithDirectories(directoriesToMonitor),
-	)
+		collector.WithTargets(cfg.Directories),
+		collector.WithNamespace(cfg.MetricNamespace),
+		collector.WithWalkerOptions(walker.WithOneFileSystem(cfg.OneFileSystem)),
+		collector.WithScanInterval(time.Duration(cfg.ScanInterval)),
+		collector.WithGrowthWindow(time.Duration(cfg.GrowthWindow)),
+	}
+
+	if cfg.Webhook.URL != "" {
+		webhook := notifier.NewWebhook(cfg.Webhook.URL,
+			notifier.WithLogger(logger),
+			notifier.WithRetries(cfg.Webhook.Retries),
+			notifier.WithRepeatInterval(time.Duration(cfg.Webhook.RepeatInterval)),
+		)
+		collectorOpts = append(collectorOpts,
+			collector.WithNotifier(webhook),
+			collector.WithGrowthAlertPercent(cfg.Webhook.GrowthPercent),
+		)
+	}
+
+	// Initialize and register collector
+	dirsizeCollector := collector.NewDirectoryCollector(collectorOpts...)
 
 	prometheus.MustRegister(dirsizeCollector)
 
+	// Start scanning the directories in the background
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dirsizeCollector.Start(ctx)
+
+	// Only the directories list can be changed without a restart
+	reload := func() error {
+		newCfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+
+		dirsizeCollector.SetTargets(newCfg.Directories)
+		logger.Info("configuration reloaded", zap.Int("directories", len(newCfg.Directories)))
+
+		return nil
+	}
+
+	go handleReloadSignal(ctx, logger, reload)
+
 	// Create metrics server
 	metricsServer := server.NewMetricsServer(
 		server.WithLogger(logger),
-		server.WithPort(metricsPort),
-		server.WithPath(metricsPath),
+		server.WithListenAddresses(listenAddresses...),
+		server.WithSystemdSocket(systemdSocket),
+		server.WithPath(cfg.MetricsPath),
+		server.WithWebConfigFile(webConfigFile),
+		server.WithReloadFunc(reload),
+		server.WithCollector(dirsizeCollector),
 	)
 
 	return metricsServer.Start()
 }
+
+// handleReloadSignal reloads the configuration every time a SIGHUP signal is received, until the context is cancelled
+func handleReloadSignal(ctx context.Context, logger *zap.Logger, reload func() error) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			logger.Info("reloading configuration", zap.String("trigger", "signal"))
+			if err := reload(); err != nil {
+				logger.Error("failed to reload configuration", zap.Error(err))
+			}
+		}
+	}
+}
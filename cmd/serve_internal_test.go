@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestLoadConfig_WithDirectoriesWithSameBaseName_SetsUniqueNames(t *testing.T) {
+	root := t.TempDir()
+	first, second := filepath.Join(root, "a", "log"), filepath.Join(root, "b", "log")
+
+	serveCmd := NewServeCmd(zap.NewNop())
+	require.NoError(t, serveCmd.ParseFlags([]string{"--directories", first + string(filepath.ListSeparator) + second}))
+
+	cfg, err := loadConfig(serveCmd)
+	require.NoError(t, err)
+
+	require.Len(t, cfg.Directories, 2)
+	assert.Equal(t, "a/log", cfg.Directories[0].Name)
+	assert.Equal(t, "b/log", cfg.Directories[1].Name)
+}
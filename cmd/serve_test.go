@@ -0,0 +1,83 @@
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/brpaz/prom-dirsize-exporter/cmd"
+)
+
+func TestServeCmd_WithMissingConfigFile_ReturnsError(t *testing.T) {
+	serveCmd := cmd.NewServeCmd(zap.NewNop())
+	serveCmd.SetArgs([]string{"--config", filepath.Join(t.TempDir(), "missing.yaml")})
+	serveCmd.SilenceUsage = true
+
+	err := serveCmd.Execute()
+
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestServeCmd_WithInvalidConfig_ReturnsError(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte("directories:\n  - name: data\n"), 0o600))
+
+	serveCmd := cmd.NewServeCmd(zap.NewNop())
+	serveCmd.SetArgs([]string{"--config", configFile})
+	serveCmd.SilenceUsage = true
+
+	err := serveCmd.Execute()
+
+	assert.ErrorContains(t, err, "invalid configuration: directories[0]: path is required")
+}
+
+func TestServeCmd_FlagsOverrideConfigFile(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte("metrics_port: 9100\n"), 0o600))
+
+	serveCmd := cmd.NewServeCmd(zap.NewNop())
+	serveCmd.SetArgs([]string{"--config", configFile, "--metrics-port", "0"})
+	serveCmd.SilenceUsage = true
+
+	err := serveCmd.Execute()
+
+	assert.ErrorContains(t, err, "metrics_port must be between 1 and 65535, got 0")
+}
+
+func TestServeCmd_WithInvalidMetricNamespace_ReturnsError(t *testing.T) {
+	serveCmd := cmd.NewServeCmd(zap.NewNop())
+	serveCmd.SetArgs([]string{"--directories", "/tmp", "--metric-namespace", "my-exporter"})
+	serveCmd.SilenceUsage = true
+
+	err := serveCmd.Execute()
+
+	assert.ErrorContains(t, err, "metric_namespace must be a valid metric name, got \"my-exporter\"")
+}
+
+func TestSetFlagsFromEnv(t *testing.T) {
+	t.Setenv("METRICS_PORT", "9100")
+	t.Setenv("CONFIG_FILE", "/etc/exporter.yaml")
+	t.Setenv("METRIC_NAMESPACE", "dirsize")
+	t.Setenv("WEB_LISTEN_ADDRESS", "127.0.0.1:9100,unix:/run/exporter.sock")
+
+	serveCmd := cmd.NewServeCmd(zap.NewNop())
+	require.NoError(t, serveCmd.ParseFlags([]string{"--metrics-path", "/custom"}))
+
+	require.NoError(t, cmd.SetFlagsFromEnv(serveCmd))
+
+	port, _ := serveCmd.Flags().GetInt("metrics-port")
+	configFile, _ := serveCmd.Flags().GetString("config")
+	metricsPath, _ := serveCmd.Flags().GetString("metrics-path")
+	namespace, _ := serveCmd.Flags().GetString("metric-namespace")
+	listenAddresses, _ := serveCmd.Flags().GetStringSlice("web.listen-address")
+
+	assert.Equal(t, 9100, port)
+	assert.Equal(t, "/etc/exporter.yaml", configFile)
+	assert.Equal(t, "/custom", metricsPath)
+	assert.Equal(t, "dirsize", namespace)
+	assert.Equal(t, []string{"127.0.0.1:9100", "unix:/run/exporter.sock"}, listenAddresses)
+}
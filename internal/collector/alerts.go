@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/brpaz/prom-dirsize-exporter/internal/notifier"
+)
+
+// alertQueueSize is the number of alerts that can wait to be sent. Alerts raised while the queue is full
+// are dropped, so a slow or unreachable notifier never blocks the scans.
+const alertQueueSize = 100
+
+// newAlert creates an alert of the given type with the current values of the directory.
+// The previous size is the one of the scan before, used to calculate the growth.
+func newAlert(alertType string, directory string, state *directoryState, previousSize int64) notifier.Alert {
+	size := state.trackedSize()
+
+	alert := notifier.Alert{
+		Type:              alertType,
+		Name:              state.name,
+		Path:              directory,
+		SizeBytes:         size,
+		PreviousSizeBytes: previousSize,
+		Files:             state.counts.files,
+		Timestamp:         state.lastScan,
+	}
+
+	if previousSize > 0 {
+		alert.GrowthPercent = float64(size-previousSize) / float64(previousSize) * 100
+	}
+
+	return alert
+}
+
+// notify queues the alerts to be sent by the notifier goroutine, if there is a notifier
+func (c *DirectoryCollector) notify(alerts []notifier.Alert) {
+	if c.alerts == nil {
+		return
+	}
+
+	for _, alert := range alerts {
+		select {
+		case c.alerts <- alert:
+		default:
+			c.logger.Warn("alert queue is full, dropping alert",
+				zap.String("type", alert.Type),
+				zap.String("directory", alert.Path),
+			)
+		}
+	}
+}
+
+// runNotifier sends the queued alerts to the notifier until the context is cancelled.
+// Failures are only logged, so they don't affect the scans.
+func (c *DirectoryCollector) runNotifier(ctx context.Context, alerts <-chan notifier.Alert) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case alert := <-alerts:
+			if err := c.notifier.Notify(ctx, alert); err != nil {
+				c.logger.Error("failed to send alert",
+					zap.String("type", alert.Type),
+					zap.String("directory", alert.Path),
+					zap.Error(err),
+				)
+			}
+		}
+	}
+}
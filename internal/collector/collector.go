@@ -2,37 +2,233 @@
 package collector
 
 import (
-	"fmt"
-	"os"
-	"os/exec"
+	"context"
+	"errors"
+	"io/fs"
 	"path/filepath"
-	"strings"
+	"sort"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
+
+	"github.com/brpaz/prom-dirsize-exporter/internal/config"
+	"github.com/brpaz/prom-dirsize-exporter/internal/filesystem"
+	"github.com/brpaz/prom-dirsize-exporter/internal/glob"
+	"github.com/brpaz/prom-dirsize-exporter/internal/notifier"
+	"github.com/brpaz/prom-dirsize-exporter/internal/walker"
+)
+
+const (
+	// CollectorNamespace is the default namespace of the metric names
+	CollectorNamespace = config.DefaultMetricNamespace
+	// CollectorName is the name of the directory size metric, without the namespace
+	CollectorName = "size_bytes"
+
+	// DefaultScanInterval is the default interval between scans of the same directory
+	DefaultScanInterval = config.DefaultScanInterval
+	// DefaultGrowthWindow is the default time window used to calculate the growth rate of the directories
+	DefaultGrowthWindow = config.DefaultGrowthWindow
 )
 
+// Reasons used to label scan errors
+const (
+	reasonNotFound         = "not_found"
+	reasonPermissionDenied = "permission_denied"
+	reasonError            = "error"
+	// reasonPartial counts the entries that could not be read in scans that succeeded, kept apart from the
+	// other reasons so they are only about the directory itself
+	reasonPartial = "partial"
+)
+
+// errorReasons holds all the possible scan error reasons, so the errors counter can be initialized
+// for each of them.
+var errorReasons = []string{reasonNotFound, reasonPermissionDenied, reasonError, reasonPartial}
+
+// directoryState holds the result of the scans of a directory.
+// The size and last scan time are the ones of the last successful scan.
+type directoryState struct {
+	// target is the configured directory the state belongs to, whose path might be a glob pattern
+	target       config.Directory
+	name         string
+	descs        *metricDescs
+	size         int64
+	diskUsage    int64
+	excludedSize int64
+	counts       entryCounts
+	lastScan     time.Time
+	success      bool
+	// scanError is the error of the last scan, nil when it succeeded
+	scanError    error
+	scanDuration time.Duration
+	errors       map[string]uint64
+	// subdirectories holds the largest subdirectories of the breakdown, sorted by size
+	subdirectories []subdirectorySize
+	// sizeByType holds the size of the files by type, nil when the breakdown by file type is disabled
+	sizeByType map[string]int64
+	// sizeByUser and sizeByGroup hold the largest owners of the directory, nil when the owners are not tracked
+	sizeByUser  []ownerSize
+	sizeByGroup []ownerSize
+	// sizeByAge holds the cumulative size of the files in each of the age buckets of the target, plus all the files
+	sizeByAge []int64
+	// oldestFile and newestFile hold the modification times of the oldest and newest files, zero without files
+	oldestFile time.Time
+	newestFile time.Time
+	// growth holds the recent sizes of the directory, to calculate its growth rate
+	growth *growthWindow
+	// filesystem holds the capacity of the filesystem of the directory, nil when it's not known
+	filesystem *filesystem.Info
+	// quotaStatus is the status of the directory against its quota, only relevant when the quota is enabled
+	quotaStatus quotaStatus
+	// largestFiles and largestDirectories hold the largest entries found in the directory, sorted by size
+	largestFiles       []walker.Entry
+	largestDirectories []walker.Entry
+}
+
+// Values of the "limit" label of the quota metrics
 const (
-	CollectorNamespace = "directory"
-	CollectorName      = "size_bytes"
+	quotaLimitSoft = "soft"
+	quotaLimitHard = "hard"
 )
 
-// DirectoryCollector collects directory size metrics
+// Values of the "type" label of the largest entries
+const (
+	entryTypeFile      = "file"
+	entryTypeDirectory = "directory"
+)
+
+// LargestEntries holds the largest files and subdirectories found in the last successful scan of a directory
+type LargestEntries struct {
+	Name        string
+	Path        string
+	ScannedAt   time.Time
+	Files       []walker.Entry
+	Directories []walker.Entry
+}
+
+// entryCounts holds the number of entries of each type found in a directory
+type entryCounts struct {
+	files       int64
+	directories int64
+	symlinks    int64
+}
+
+// subdirectorySize holds the size of a subdirectory of the breakdown
+type subdirectorySize struct {
+	// relPath is the path of the subdirectory relative to its root, using forward slashes
+	relPath string
+	size    int64
+}
+
+// newDirectoryState creates a new directoryState for a directory of the target, with all the error counters initialized
+func newDirectoryState(target config.Directory, path string, descs *metricDescs, growthWindow time.Duration) *directoryState {
+	name := target.LabelName()
+	if path != target.Path {
+		name = matchName(target.Path, path)
+	}
+
+	state := &directoryState{
+		target: target,
+		name:   name,
+		descs:  descs,
+		errors: make(map[string]uint64, len(errorReasons)),
+		growth: newGrowthWindow(growthWindow),
+	}
+
+	for _, reason := range errorReasons {
+		state.errors[reason] = 0
+	}
+
+	return state
+}
+
+// DirectoryCollector collects directory size metrics.
+// Directories are scanned in the background by the scheduler started with Start, and Collect only
+// exposes the last known values, so scrapes are never blocked by slow scans.
 type DirectoryCollector struct {
-	logger      *zap.Logger
-	directories []string
-	mutex       sync.Mutex
-	metricsMap  map[string]prometheus.Gauge
+	logger        *zap.Logger
+	targets       []config.Directory
+	walkerOptions []walker.Option
+	scanInterval  time.Duration
+	growthWindow  time.Duration
+	// namespace is the namespace of the metric names
+	namespace string
+	// notifier receives the alerts raised by the scans, nil when alerts are disabled
+	notifier notifier.Notifier
+	// alerts queues the alerts to send to the notifier, nil until Start is called or when alerts are disabled
+	alerts             chan notifier.Alert
+	growthAlertPercent float64
+	ownerNames         *ownerNames
+	mutex              sync.RWMutex
+	states             map[string]*directoryState
+
+	// ctx is the context the scheduler was started with, nil until Start is called
+	ctx       context.Context
+	schedules map[string]*schedule
 }
 
 // DirectoryCollectorOption represents an option to customize DirectoryCollector behavior
 type DirectoryCollectorOption func(*DirectoryCollector)
 
-// WithDirectories sets the directories to monitor
+// WithDirectories sets the directories to monitor, using the default settings for each of them
 func WithDirectories(dirs []string) DirectoryCollectorOption {
 	return func(c *DirectoryCollector) {
-		c.directories = dirs
+		c.targets = make([]config.Directory, 0, len(dirs))
+		for _, dir := range dirs {
+			c.targets = append(c.targets, config.Directory{Path: dir})
+		}
+	}
+}
+
+// WithTargets sets the directories to monitor, each with its own settings
+func WithTargets(targets []config.Directory) DirectoryCollectorOption {
+	return func(c *DirectoryCollector) {
+		c.targets = targets
+	}
+}
+
+// WithWalkerOptions sets the options of the walker used to calculate the size of every directory
+func WithWalkerOptions(opts ...walker.Option) DirectoryCollectorOption {
+	return func(c *DirectoryCollector) {
+		c.walkerOptions = opts
+	}
+}
+
+// WithScanInterval sets the default interval between scans of the same directory
+func WithScanInterval(interval time.Duration) DirectoryCollectorOption {
+	return func(c *DirectoryCollector) {
+		c.scanInterval = interval
+	}
+}
+
+// WithGrowthWindow sets the time window of the samples used to calculate the growth rate of each directory
+func WithGrowthWindow(window time.Duration) DirectoryCollectorOption {
+	return func(c *DirectoryCollector) {
+		c.growthWindow = window
+	}
+}
+
+// WithNamespace sets the namespace of the metric names, used instead of CollectorNamespace
+func WithNamespace(namespace string) DirectoryCollectorOption {
+	return func(c *DirectoryCollector) {
+		c.namespace = namespace
+	}
+}
+
+// WithNotifier sets the notifier that receives the alerts raised by the scans, like quota status changes
+func WithNotifier(n notifier.Notifier) DirectoryCollectorOption {
+	return func(c *DirectoryCollector) {
+		c.notifier = n
+	}
+}
+
+// WithGrowthAlertPercent raises an alert when a directory grows more than the given percentage between two
+// consecutive scans. 0 disables it.
+func WithGrowthAlertPercent(percent float64) DirectoryCollectorOption {
+	return func(c *DirectoryCollector) {
+		c.growthAlertPercent = percent
 	}
 }
 
@@ -46,8 +242,13 @@ func WithLogger(logger *zap.Logger) DirectoryCollectorOption {
 // NewDirectoryCollector creates a new DirectoryCollector with the provided options
 func NewDirectoryCollector(opts ...DirectoryCollectorOption) *DirectoryCollector {
 	collector := &DirectoryCollector{
-		logger:     zap.NewNop(),
-		metricsMap: make(map[string]prometheus.Gauge),
+		logger:       zap.NewNop(),
+		scanInterval: DefaultScanInterval,
+		growthWindow: DefaultGrowthWindow,
+		namespace:    CollectorNamespace,
+		ownerNames:   newOwnerNames(),
+		states:       make(map[string]*directoryState),
+		schedules:    make(map[string]*schedule),
 	}
 
 	// Apply options
@@ -58,94 +259,436 @@ func NewDirectoryCollector(opts ...DirectoryCollectorOption) *DirectoryCollector
 	return collector
 }
 
-// Directories returns the directories being monitored
+// Directories returns the paths of the directories being monitored
 func (c *DirectoryCollector) Directories() []string {
-	return c.directories
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.directories()
+}
+
+// directories returns the paths of the directories being monitored. Must be called with the mutex locked.
+func (c *DirectoryCollector) directories() []string {
+	dirs := make([]string, 0, len(c.targets))
+	for _, target := range c.targets {
+		dirs = append(dirs, target.Path)
+	}
+
+	return dirs
 }
 
 // Describe implements the prometheus.Collector interface.
-func (c *DirectoryCollector) Describe(ch chan<- *prometheus.Desc) {
-	for _, metric := range c.metricsMap {
-		metric.Describe(ch)
+// It sends no descriptors, making this an unchecked collector, as the metric prefixes and the labels of the
+// directories change with the config reloads and the matches of the glob patterns.
+func (c *DirectoryCollector) Describe(_ chan<- *prometheus.Desc) {}
+
+// metricDescsFor creates the metric descriptors of a directory of the target. The labels captured from its path
+// by the target pattern are added to the labels of the target.
+func (c *DirectoryCollector) metricDescsFor(target config.Directory, captures map[string]string) *metricDescs {
+	labels := prometheus.Labels(target.Labels)
+	if len(captures) > 0 {
+		labels = make(prometheus.Labels, len(target.Labels)+len(captures))
+		for label, value := range target.Labels {
+			labels[label] = value
+		}
+		for label, value := range captures {
+			labels[label] = value
+		}
 	}
+
+	return newMetricDescs(c.namespace, target.MetricPrefix, labels)
 }
 
 // Collect implements the prometheus.Collector interface.
+// It sends the values of the last completed scan of each directory.
 func (c *DirectoryCollector) Collect(ch chan<- prometheus.Metric) {
-	var wg sync.WaitGroup
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	for directory, state := range c.states {
+		labels := []string{state.name, directory}
+		descs := state.descs
 
-	c.logger.Info("start collector", zap.String("directories", strings.Join(c.directories, ",")))
+		ch <- prometheus.MustNewConstMetric(descs.scanSuccess, prometheus.GaugeValue, boolToFloat(state.success), labels...)
+		ch <- prometheus.MustNewConstMetric(descs.scanDuration, prometheus.GaugeValue, state.scanDuration.Seconds(), labels...)
 
-	for _, dir := range c.directories {
+		for reason, count := range state.errors {
+			ch <- prometheus.MustNewConstMetric(descs.scanErrors, prometheus.CounterValue, float64(count), append(labels, reason)...)
+		}
 
-		// before processing, check if the directory exists
-		if _, err := os.Stat(dir); os.IsNotExist(err) {
-			c.logger.Error("directory does not exist", zap.String("directory", dir))
+		// Only report the size when the directory was scanned successfully at least once
+		if state.lastScan.IsZero() {
 			continue
 		}
 
-		wg.Add(1)
-		go func(directory string) {
-			c.logger.Info("collecting directory size", zap.String("directory", directory))
-			defer wg.Done()
+		collectSizes(ch, directory, state)
+		ch <- prometheus.MustNewConstMetric(descs.files, prometheus.GaugeValue, float64(state.counts.files), labels...)
+		ch <- prometheus.MustNewConstMetric(descs.directories, prometheus.GaugeValue, float64(state.counts.directories), labels...)
+		ch <- prometheus.MustNewConstMetric(descs.symlinks, prometheus.GaugeValue, float64(state.counts.symlinks), labels...)
+		ch <- prometheus.MustNewConstMetric(descs.excluded, prometheus.GaugeValue, float64(state.excludedSize), labels...)
+		collectOwners(ch, descs.sizeByUser, labels, state.sizeByUser)
+		collectOwners(ch, descs.sizeByGroup, labels, state.sizeByGroup)
+		for fileType, size := range state.sizeByType {
+			ch <- prometheus.MustNewConstMetric(descs.sizeByType, prometheus.GaugeValue, float64(size), append(labels, fileType)...)
+		}
+		ch <- prometheus.MustNewConstMetric(descs.lastScan, prometheus.GaugeValue, float64(state.lastScan.Unix()), labels...)
+
+		collectFileAges(ch, labels, state)
+		collectGrowth(ch, labels, state)
+		collectFilesystem(ch, labels, state)
+		collectQuota(ch, labels, state)
+		collectLargestEntries(ch, descs.largestEntry, labels, entryTypeFile, state.largestFiles)
+		collectLargestEntries(ch, descs.largestEntry, labels, entryTypeDirectory, state.largestDirectories)
+	}
+}
+
+// collectSizes sends the size metrics of the directory according to its size mode
+func collectSizes(ch chan<- prometheus.Metric, directory string, state *directoryState) {
+	labels := []string{state.name, directory}
+
+	if state.target.ReportsDiskUsage() {
+		ch <- prometheus.MustNewConstMetric(state.descs.diskUsage, prometheus.GaugeValue, float64(state.diskUsage), labels...)
+	}
+
+	if !state.target.ReportsApparentSize() {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(state.descs.size, prometheus.GaugeValue, float64(state.size), append(labels, "")...)
+
+	for _, subdir := range state.subdirectories {
+		ch <- prometheus.MustNewConstMetric(state.descs.size, prometheus.GaugeValue, float64(subdir.size),
+			state.name+"/"+subdir.relPath,
+			filepath.Join(directory, filepath.FromSlash(subdir.relPath)),
+			directory,
+		)
+	}
+}
+
+// collectOwners sends the size of the entries of each owner
+func collectOwners(ch chan<- prometheus.Metric, desc *prometheus.Desc, labels []string, owners []ownerSize) {
+	for _, owner := range owners {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(owner.size), append(labels, owner.name)...)
+	}
+}
+
+// collectFileAges sends the modification times of the oldest and newest files and the size of the files by age
+func collectFileAges(ch chan<- prometheus.Metric, labels []string, state *directoryState) {
+	if !state.oldestFile.IsZero() {
+		ch <- prometheus.MustNewConstMetric(state.descs.oldestFile, prometheus.GaugeValue, float64(state.oldestFile.Unix()), labels...)
+		ch <- prometheus.MustNewConstMetric(state.descs.newestFile, prometheus.GaugeValue, float64(state.newestFile.Unix()), labels...)
+	}
+
+	buckets := state.target.AgeBucketDurations()
+	for i, size := range state.sizeByAge {
+		le := "+Inf"
+		if i < len(buckets) {
+			le = strconv.FormatFloat(buckets[i].Seconds(), 'f', -1, 64)
+		}
+
+		ch <- prometheus.MustNewConstMetric(state.descs.sizeByAge, prometheus.GaugeValue, float64(size), append(labels, le)...)
+	}
+}
+
+// collectGrowth sends the growth rate of the directory and, when it grows and the free space of its filesystem
+// is known, the estimated time until the filesystem is full
+func collectGrowth(ch chan<- prometheus.Metric, labels []string, state *directoryState) {
+	rate, ok := state.growth.rate()
+	if !ok {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(state.descs.growthRate, prometheus.GaugeValue, rate, labels...)
+
+	if rate > 0 && state.filesystem != nil {
+		ch <- prometheus.MustNewConstMetric(state.descs.timeUntilFull, prometheus.GaugeValue, float64(state.filesystem.Free)/rate, labels...)
+	}
+}
+
+// collectFilesystem sends the capacity of the filesystem of the directory and how much of it the directory uses
+func collectFilesystem(ch chan<- prometheus.Metric, labels []string, state *directoryState) {
+	info := state.filesystem
+	if info == nil {
+		return
+	}
+
+	labels = append(labels, info.Mountpoint, info.Type)
+
+	ch <- prometheus.MustNewConstMetric(state.descs.filesystemSize, prometheus.GaugeValue, float64(info.Size), labels...)
+	ch <- prometheus.MustNewConstMetric(state.descs.filesystemFree, prometheus.GaugeValue, float64(info.Free), labels...)
+
+	if info.Size > 0 {
+		ch <- prometheus.MustNewConstMetric(state.descs.filesystemUsage, prometheus.GaugeValue, float64(state.trackedSize())/float64(info.Size), labels...)
+	}
+}
+
+// collectQuota sends the limits of the quota of the directory and its status against them
+func collectQuota(ch chan<- prometheus.Metric, labels []string, state *directoryState) {
+	quota := state.target.Quota
+	if !quota.Enabled() {
+		return
+	}
+
+	limits := []struct {
+		desc  *prometheus.Desc
+		limit string
+		value int64
+	}{
+		{state.descs.quotaBytes, quotaLimitSoft, quota.SoftBytes},
+		{state.descs.quotaBytes, quotaLimitHard, quota.HardBytes},
+		{state.descs.quotaFiles, quotaLimitSoft, quota.SoftFiles},
+		{state.descs.quotaFiles, quotaLimitHard, quota.HardFiles},
+	}
+	for _, l := range limits {
+		if l.value > 0 {
+			ch <- prometheus.MustNewConstMetric(l.desc, prometheus.GaugeValue, float64(l.value), append(labels, l.limit)...)
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(state.descs.quotaStatus, prometheus.GaugeValue, float64(state.quotaStatus), labels...)
+}
 
-			size, err := c.getDirectorySize(directory)
-			if err != nil {
-				c.logger.Error("error getting directory size", zap.String("directory", directory), zap.Error(err))
-				return
-			}
+// collectLargestEntries sends a metric for each of the entries, ranked by their position
+func collectLargestEntries(ch chan<- prometheus.Metric, desc *prometheus.Desc, labels []string, entryType string, entries []walker.Entry) {
+	for i, entry := range entries {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(entry.Size),
+			append(labels, strconv.Itoa(i+1), entry.Path, entryType)...,
+		)
+	}
+}
 
-			c.logger.Info("directory size collected", zap.String("directory", directory), zap.Int64("size", size))
+// LargestEntries returns the largest files and subdirectories of the directory with the given name.
+// It fails with ErrDirectoryNotFound if no directory with that name was scanned successfully yet, and an
+// AmbiguousNameError if there are several directories with the name.
+func (c *DirectoryCollector) LargestEntries(name string) (LargestEntries, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
 
-			c.updateMetric(directory, size, ch)
-		}(dir)
+	path, err := c.lookup(name)
+	if err != nil {
+		return LargestEntries{}, err
+	}
+
+	state, ok := c.states[path]
+	if !ok || state.lastScan.IsZero() {
+		return LargestEntries{}, ErrDirectoryNotFound
 	}
 
-	// Wait for all goroutines to finish
-	wg.Wait()
+	return LargestEntries{
+		Name:        state.name,
+		Path:        path,
+		ScannedAt:   state.lastScan,
+		Files:       state.largestFiles,
+		Directories: state.largestDirectories,
+	}, nil
+}
+
+// scanResult holds the outcome of a single directory scan
+type scanResult struct {
+	// labels holds the labels captured from the path of the directory by the target pattern
+	labels             map[string]string
+	size               int64
+	diskUsage          int64
+	excludedSize       int64
+	counts             entryCounts
+	subdirectories     map[string]int64
+	sizeByType         map[string]int64
+	sizeByAge          []int64
+	sizeByUser         []ownerSize
+	sizeByGroup        []ownerSize
+	oldestFile         time.Time
+	newestFile         time.Time
+	filesystem         *filesystem.Info
+	largestFiles       []walker.Entry
+	largestDirectories []walker.Entry
+	scannedAt          time.Time
+	duration           time.Duration
+	// err is the error that made the scan fail, if any
+	err error
+	// entryErrors holds the errors of the entries that could not be read in a successful scan
+	entryErrors []error
 }
 
-// updateMetric updates or creates a new metric for the given directory.
-func (c *DirectoryCollector) updateMetric(directory string, size int64, ch chan<- prometheus.Metric) {
+// updateState stores the result of the scan of a directory of the target, and returns the alerts it raised.
+// Results of scans whose schedule was cancelled in the meantime are discarded, so removed directories
+// don't reappear.
+func (c *DirectoryCollector) updateState(ctx context.Context, target config.Directory, path string, result scanResult) []notifier.Alert {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	metric, ok := c.metricsMap[directory]
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	state, ok := c.states[path]
 	if !ok {
-		metric = prometheus.NewGauge(prometheus.GaugeOpts{
-			Namespace:   CollectorNamespace,
-			Name:        CollectorName,
-			Help:        "Size of the directory in bytes.",
-			ConstLabels: prometheus.Labels{"name": filepath.Base(directory), "path": directory},
-		})
-		c.metricsMap[directory] = metric
+		state = newDirectoryState(target, path, c.metricDescsFor(target, result.labels), c.growthWindow)
+		c.states[path] = state
+	}
+
+	state.scanDuration = result.duration
+	state.success = result.err == nil
+	state.scanError = result.err
+
+	if result.err != nil {
+		state.errors[errorReason(result.err)]++
+		return nil
 	}
-	metric.Set(float64(size))
-	ch <- metric
+
+	state.errors[reasonPartial] += uint64(len(result.entryErrors))
+
+	previousSize, scannedBefore := state.trackedSize(), !state.lastScan.IsZero()
+
+	state.size = result.size
+	state.diskUsage = result.diskUsage
+	state.excludedSize = result.excludedSize
+	state.counts = result.counts
+	state.lastScan = result.scannedAt
+	state.subdirectories = largestSubdirectories(result.subdirectories, target.SubdirectoriesLimit())
+	state.sizeByType = result.sizeByType
+	state.sizeByAge = result.sizeByAge
+	state.sizeByUser = result.sizeByUser
+	state.sizeByGroup = result.sizeByGroup
+	state.oldestFile = result.oldestFile
+	state.newestFile = result.newestFile
+	state.growth.add(result.scannedAt, state.trackedSize())
+	state.filesystem = result.filesystem
+	state.largestFiles = result.largestFiles
+	state.largestDirectories = result.largestDirectories
+
+	var alerts []notifier.Alert
+	if previousStatus, changed := c.updateQuotaStatus(path, state); changed {
+		alert := newAlert(notifier.AlertTypeQuota, path, state, previousSize)
+		alert.Status = state.quotaStatus.String()
+		alert.PreviousStatus = previousStatus.String()
+		alerts = append(alerts, alert)
+	}
+
+	if scannedBefore && c.growthAlertPercent > 0 {
+		if alert := newAlert(notifier.AlertTypeGrowth, path, state, previousSize); alert.GrowthPercent > c.growthAlertPercent {
+			alerts = append(alerts, alert)
+		}
+	}
+
+	return alerts
 }
 
-// getDirectorySize calculates the total size of a directory using the "du" command
-func (c *DirectoryCollector) getDirectorySize(path string) (int64, error) {
-	cmd := exec.Command("du", "-sb", path)
+// trackedSize returns the size used to calculate the growth and filesystem usage of the directory, which is
+// the space allocated on disk when it's the only size reported and the apparent size otherwise
+func (s *directoryState) trackedSize() int64 {
+	if !s.target.ReportsApparentSize() {
+		return s.diskUsage
+	}
+
+	return s.size
+}
+
+// largestSubdirectories returns up to limit subdirectories, sorted from the largest to the smallest
+func largestSubdirectories(sizes map[string]int64, limit int) []subdirectorySize {
+	subdirs := make([]subdirectorySize, 0, len(sizes))
+	for relPath, size := range sizes {
+		subdirs = append(subdirs, subdirectorySize{relPath: relPath, size: size})
+	}
+
+	sort.Slice(subdirs, func(i, j int) bool {
+		if subdirs[i].size != subdirs[j].size {
+			return subdirs[i].size > subdirs[j].size
+		}
+
+		return subdirs[i].relPath < subdirs[j].relPath
+	})
+
+	if len(subdirs) > limit {
+		subdirs = subdirs[:limit]
+	}
+
+	return subdirs
+}
 
-	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0o644)
+// matchName returns the name of a directory that matched a glob pattern, which is its path relative
+// to the static prefix of the pattern. For example, "/srv/tenants/a/uploads" matched by "/srv/tenants/*/uploads"
+// is named "a/uploads".
+func matchName(pattern string, path string) string {
+	name, err := filepath.Rel(glob.StaticPrefix(pattern), path)
 	if err != nil {
-		c.logger.Error("error opening /dev/null", zap.Error(err))
+		return filepath.Base(path)
 	}
-	defer devNull.Close()
 
-	cmd.Stderr = devNull
+	return filepath.ToSlash(name)
+}
+
+// errorReason returns the reason label value that best describes the error
+func errorReason(err error) string {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return reasonNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return reasonPermissionDenied
+	default:
+		return reasonError
+	}
+}
 
-	// TODO Ignoring the error is not pretty, but du always return error exit status,
-	// even for not "fatal" errors like permission denied
-	output, _ := cmd.Output()
+// boolToFloat converts a boolean to a metric value
+func boolToFloat(value bool) float64 {
+	if value {
+		return 1
+	}
 
-	sizeStr := strings.Fields(string(output))[0]
-	var size int64
-	_, err = fmt.Sscanf(sizeStr, "%d", &size)
+	return 0
+}
+
+// pruneStates removes the states of the directories of the target that are not in paths anymore
+func (c *DirectoryCollector) pruneStates(ctx context.Context, target config.Directory, paths []string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	current := make(map[string]struct{}, len(paths))
+	for _, path := range paths {
+		current[path] = struct{}{}
+	}
+
+	for path, state := range c.states {
+		if _, ok := current[path]; !ok && state.target.Path == target.Path {
+			c.logger.Info("directory no longer matches pattern, removing it",
+				zap.String("directory", path),
+				zap.String("pattern", target.Path),
+			)
+			delete(c.states, path)
+		}
+	}
+}
+
+// getDirectorySize calculates the total size of a directory of the target by walking its tree.
+// Entries that cannot be read are skipped and logged, so the returned size might be partial.
+func (c *DirectoryCollector) getDirectorySize(ctx context.Context, target config.Directory, path string) (*walker.Result, error) {
+	opts := append([]walker.Option{}, c.walkerOptions...)
+	opts = append(opts,
+		walker.WithIncludes(target.Include),
+		walker.WithExcludes(target.Exclude),
+		walker.WithDepth(target.Depth),
+		walker.WithTopEntries(target.TopEntries),
+		walker.WithFileTypes(target.FileTypeExtensions()),
+		walker.WithAgeBuckets(target.AgeBucketDurations()),
+		walker.WithOwners(target.TopOwners > 0),
+	)
+
+	result, err := walker.New(opts...).Walk(ctx, path)
 	if err != nil {
-		return 0, fmt.Errorf("error parsing size: %w", err)
+		return nil, err
+	}
+
+	if result.Partial() {
+		c.logger.Warn("some entries could not be read, directory size is partial",
+			zap.String("directory", path),
+			zap.Int("errors", len(result.Errors)),
+			zap.Error(result.Errors[0]),
+		)
 	}
 
-	return size, nil
+	return result, nil
 }
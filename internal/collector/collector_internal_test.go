@@ -0,0 +1,30 @@
+package collector
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/brpaz/prom-dirsize-exporter/internal/config"
+)
+
+func TestDirectoryCollector_UpdateState_CountsEntryErrorsAsPartial(t *testing.T) {
+	t.Parallel()
+
+	c := NewDirectoryCollector()
+	target := config.Directory{Path: "/srv/data"}
+
+	c.updateState(context.Background(), target, target.Path, scanResult{
+		entryErrors: []error{fs.ErrPermission, fs.ErrNotExist},
+	})
+
+	assert.Equal(t, map[string]uint64{
+		reasonNotFound:         0,
+		reasonPermissionDenied: 0,
+		reasonError:            0,
+		reasonPartial:          2,
+	}, c.states[target.Path].errors)
+	assert.True(t, c.states[target.Path].success)
+}
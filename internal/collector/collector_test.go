@@ -1,16 +1,60 @@
 package collector_test
 
 import (
+	"context"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
 	"testing"
 	"time"
 
-	"github.com/brpaz/prom-dirsize-exporter/internal/collector"
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/brpaz/prom-dirsize-exporter/internal/collector"
+	"github.com/brpaz/prom-dirsize-exporter/internal/config"
+	"github.com/brpaz/prom-dirsize-exporter/internal/notifier"
+	"github.com/brpaz/prom-dirsize-exporter/internal/walker"
 )
 
+// findMetricFamily gathers the metrics from the registry and returns the family with the given name
+func findMetricFamily(t *testing.T, registry *prometheus.Registry, name string) *dto.MetricFamily {
+	t.Helper()
+
+	metrics, err := registry.Gather()
+	require.NoError(t, err)
+
+	for _, mf := range metrics {
+		if mf.GetName() == name {
+			return mf
+		}
+	}
+
+	return nil
+}
+
+// counterValuesByLabel returns the values of a counter family indexed by the value of the given label
+func counterValuesByLabel(mf *dto.MetricFamily, labelName string) map[string]float64 {
+	values := make(map[string]float64)
+
+	for _, metric := range mf.Metric {
+		for _, label := range metric.Label {
+			if label.GetName() == labelName {
+				values[label.GetValue()] = metric.Counter.GetValue()
+			}
+		}
+	}
+
+	return values
+}
+
 func TestNewDirectoryCollector(t *testing.T) {
 	c := collector.NewDirectoryCollector(
 		collector.WithDirectories([]string{"/tmp"}),
@@ -25,31 +69,244 @@ func TestDirectoryCollector_Collect(t *testing.T) {
 		collector.WithDirectories([]string{"./testdata/example_directory"}),
 	)
 
-	// Create a new Prometheus registry
-	// TODO is this the best way to test this?
 	registry := prometheus.NewRegistry()
-	prometheus.DefaultRegisterer = registry
 	registry.MustRegister(c)
 
-	ch := make(chan prometheus.Metric, 1)
-	defer close(ch)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
 
-	go func() {
-		c.Collect(ch)
-	}()
+	c.Start(ctx)
 
-	select {
-	case metric := <-ch:
-		assert.NotNil(t, metric)
-		assert.Implements(t, (*prometheus.Gauge)(nil), metric)
+	require.Eventually(t, func() bool {
+		return findMetricFamily(t, registry, "directory_size_bytes") != nil
+	}, time.Second, 10*time.Millisecond)
+
+	sizeMetric := findMetricFamily(t, registry, "directory_size_bytes")
+	assert.Len(t, sizeMetric.Metric, 1)
+	assert.Greater(t, sizeMetric.Metric[0].Gauge.GetValue(), float64(0))
+
+	filesMetric := findMetricFamily(t, registry, "directory_files_total")
+	require.NotNil(t, filesMetric)
+	assert.Equal(t, float64(1), filesMetric.Metric[0].Gauge.GetValue())
+
+	subdirsMetric := findMetricFamily(t, registry, "directory_subdirectories_total")
+	require.NotNil(t, subdirsMetric)
+	assert.Equal(t, float64(0), subdirsMetric.Metric[0].Gauge.GetValue())
+
+	symlinksMetric := findMetricFamily(t, registry, "directory_symlinks_total")
+	require.NotNil(t, symlinksMetric)
+	assert.Equal(t, float64(0), symlinksMetric.Metric[0].Gauge.GetValue())
+
+	lastScanMetric := findMetricFamily(t, registry, "directory_last_scan_timestamp_seconds")
+	require.NotNil(t, lastScanMetric)
+	assert.InDelta(t, float64(time.Now().Unix()), lastScanMetric.Metric[0].Gauge.GetValue(), 5)
+}
+
+func TestDirectoryCollector_Collect_WithTargets_UsesNameAndLabels(t *testing.T) {
+	c := collector.NewDirectoryCollector(
+		collector.WithTargets([]config.Directory{
+			{
+				Path:   "./testdata/example_directory",
+				Name:   "example",
+				Labels: map[string]string{"team": "platform"},
+			},
+		}),
+	)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return findMetricFamily(t, registry, "directory_size_bytes") != nil
+	}, time.Second, 10*time.Millisecond)
+
+	labels := make(map[string]string)
+	for _, label := range findMetricFamily(t, registry, "directory_size_bytes").Metric[0].Label {
+		labels[label.GetName()] = label.GetValue()
+	}
+
+	assert.Equal(t, map[string]string{
+		"name":   "example",
+		"path":   "./testdata/example_directory",
+		"parent": "",
+		"team":   "platform",
+	}, labels)
+}
+
+func TestDirectoryCollector_Collect_WithNamespaceAndPrefix_UsesMetricNames(t *testing.T) {
+	c := collector.NewDirectoryCollector(
+		collector.WithNamespace("dirsize"),
+		collector.WithTargets([]config.Directory{
+			{Path: "./testdata/example_directory", Name: "example"},
+			{Path: "./testdata", Name: "testdata", MetricPrefix: "fixtures"},
+		}),
+	)
+
+	// The pedantic registry fails when the collected metrics are inconsistent
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return findMetricFamily(t, registry, "dirsize_size_bytes") != nil &&
+			findMetricFamily(t, registry, "dirsize_fixtures_size_bytes") != nil
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Nil(t, findMetricFamily(t, registry, "directory_size_bytes"))
+	assert.NotNil(t, findMetricFamily(t, registry, "dirsize_fixtures_last_scan_timestamp_seconds"))
+}
+
+func TestDirectoryCollector_Collect_WithDynamicLabelsAndPrefixes_PassesPedanticChecks(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "acme", "data"), 0o755))
+
+	c := collector.NewDirectoryCollector(
+		collector.WithTargets([]config.Directory{
+			{Path: "./testdata/example_directory", Name: "example", Labels: map[string]string{"team": "a"}},
+			{Path: filepath.Join(root, "(?P<tenant>[^/]+)", "data")},
+		}),
+	)
+
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return c.Readiness().Ready()
+	}, time.Second, 10*time.Millisecond)
+
+	_, err := registry.Gather()
+	require.NoError(t, err)
+
+	// Prefixes added by a reload are not known when the collector is registered
+	c.SetTargets([]config.Directory{{Path: "./testdata", Name: "testdata", MetricPrefix: "fixtures"}})
+
+	require.Eventually(t, func() bool {
+		return findMetricFamily(t, registry, "directory_fixtures_size_bytes") != nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestDirectoryCollector_Collect_BeforeFirstScan_ReturnsNoMetrics(t *testing.T) {
+	c := collector.NewDirectoryCollector(
+		collector.WithDirectories([]string{"./testdata/example_directory"}),
+	)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	metrics, err := registry.Gather()
+	require.NoError(t, err)
+	assert.Empty(t, metrics)
+}
+
+func TestDirectoryCollector_Start_RescansOnInterval(t *testing.T) {
+	dir := t.TempDir()
+
+	c := collector.NewDirectoryCollector(
+		collector.WithDirectories([]string{dir}),
+		collector.WithScanInterval(50*time.Millisecond),
+	)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return findMetricFamily(t, registry, "directory_size_bytes") != nil
+	}, time.Second, 10*time.Millisecond)
+
+	initialSize := findMetricFamily(t, registry, "directory_size_bytes").Metric[0].Gauge.GetValue()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new_file.txt"), make([]byte, 1024), 0o600))
+
+	assert.Eventually(t, func() bool {
+		size := findMetricFamily(t, registry, "directory_size_bytes").Metric[0].Gauge.GetValue()
+		return size == initialSize+1024
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestDirectoryCollector_Collect_ReportsGrowthRate(t *testing.T) {
+	dir := t.TempDir()
+
+	c := collector.NewDirectoryCollector(
+		collector.WithDirectories([]string{dir}),
+		collector.WithScanInterval(50*time.Millisecond),
+	)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return findMetricFamily(t, registry, "directory_growth_bytes_per_second") != nil
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new_file.txt"), make([]byte, 1024*1024), 0o600))
+
+	assert.Eventually(t, func() bool {
+		growth := findMetricFamily(t, registry, "directory_growth_bytes_per_second")
+		return growth.Metric[0].Gauge.GetValue() > 0 &&
+			findMetricFamily(t, registry, "directory_estimated_seconds_until_full") != nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestDirectoryCollector_Collect_ReportsFilesystem(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("filesystem information is not supported on this platform")
+	}
+
+	c := collector.NewDirectoryCollector(
+		collector.WithDirectories([]string{"./testdata/example_directory"}),
+	)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return findMetricFamily(t, registry, "directory_filesystem_size_bytes") != nil
+	}, time.Second, 10*time.Millisecond)
 
-		metrics, _ := registry.Gather()
-		assert.Equal(t, 1, len(metrics))
-		assert.Equal(t, "directory_size_bytes", metrics[0].GetName())
-		assert.Greater(t, metrics[0].Metric[0].Gauge.GetValue(), float64(0))
-	case timeout := <-time.After(1 * time.Second):
-		t.Fatalf("Timed out waiting for metric to be collected. %v", timeout)
+	sizeMetric := findMetricFamily(t, registry, "directory_filesystem_size_bytes")
+	labels := make(map[string]string)
+	for _, label := range sizeMetric.Metric[0].Label {
+		labels[label.GetName()] = label.GetValue()
 	}
+	assert.NotEmpty(t, labels["mountpoint"])
+	assert.NotEmpty(t, labels["fstype"])
+	assert.Greater(t, sizeMetric.Metric[0].Gauge.GetValue(), float64(0))
+
+	require.NotNil(t, findMetricFamily(t, registry, "directory_filesystem_free_bytes"))
+
+	usageMetric := findMetricFamily(t, registry, "directory_filesystem_usage_ratio")
+	require.NotNil(t, usageMetric)
+	assert.Greater(t, usageMetric.Metric[0].Gauge.GetValue(), float64(0))
+	assert.Less(t, usageMetric.Metric[0].Gauge.GetValue(), float64(1))
 }
 
 func TestDirectoryCollector_Collect_WithNonExistingDirectory(t *testing.T) {
@@ -62,20 +319,804 @@ func TestDirectoryCollector_Collect_WithNonExistingDirectory(t *testing.T) {
 	)
 
 	registry := prometheus.NewRegistry()
-	prometheus.DefaultRegisterer = registry
 	registry.MustRegister(c)
 
-	ch := make(chan prometheus.Metric, 1)
-	defer close(ch)
-
-	go func() {
-		c.Collect(ch)
-	}()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
 
-	time.Sleep(1 * time.Second)
-	assert.Len(t, ch, 0)
+	c.Start(ctx)
 
 	// Check if the error was logged
-	errorLog := observedLogs.FilterMessage("directory does not exist").All()
-	assert.Equal(t, 1, len(errorLog))
+	require.Eventually(t, func() bool {
+		return observedLogs.FilterMessage("directory does not exist").Len() == 1
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Nil(t, findMetricFamily(t, registry, "directory_size_bytes"))
+
+	successMetric := findMetricFamily(t, registry, "directory_scan_success")
+	require.NotNil(t, successMetric)
+	assert.Equal(t, float64(0), successMetric.Metric[0].Gauge.GetValue())
+
+	errorsMetric := findMetricFamily(t, registry, "directory_scan_errors_total")
+	require.NotNil(t, errorsMetric)
+	assert.Equal(t, map[string]float64{
+		"not_found":         1,
+		"permission_denied": 0,
+		"error":             0,
+		"partial":           0,
+	}, counterValuesByLabel(errorsMetric, "reason"))
+}
+
+func TestDirectoryCollector_Collect_WithEmptyDirectory_ReportsSuccess(t *testing.T) {
+	c := collector.NewDirectoryCollector(
+		collector.WithDirectories([]string{t.TempDir()}),
+	)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return findMetricFamily(t, registry, "directory_scan_success") != nil
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, float64(1), findMetricFamily(t, registry, "directory_scan_success").Metric[0].Gauge.GetValue())
+	assert.NotNil(t, findMetricFamily(t, registry, "directory_size_bytes"))
+	assert.NotNil(t, findMetricFamily(t, registry, "directory_scan_duration_seconds"))
+
+	errorsMetric := findMetricFamily(t, registry, "directory_scan_errors_total")
+	require.NotNil(t, errorsMetric)
+	for reason, value := range counterValuesByLabel(errorsMetric, "reason") {
+		assert.Equal(t, float64(0), value, "unexpected errors with reason %s", reason)
+	}
+}
+
+// gaugeValuesByPath returns the values of a gauge family indexed by the value of the path label
+func gaugeValuesByPath(mf *dto.MetricFamily) map[string]float64 {
+	values := make(map[string]float64)
+	if mf == nil {
+		return values
+	}
+
+	for _, metric := range mf.Metric {
+		for _, label := range metric.Label {
+			if label.GetName() == "path" {
+				values[label.GetValue()] = metric.Gauge.GetValue()
+			}
+		}
+	}
+
+	return values
+}
+
+func TestDirectoryCollector_SetTargets_SwapsDirectories(t *testing.T) {
+	keptDir := t.TempDir()
+	removedDir := t.TempDir()
+	addedDir := t.TempDir()
+
+	c := collector.NewDirectoryCollector(
+		collector.WithDirectories([]string{keptDir, removedDir}),
+	)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return len(gaugeValuesByPath(findMetricFamily(t, registry, "directory_size_bytes"))) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	c.SetTargets([]config.Directory{{Path: keptDir}, {Path: addedDir}})
+
+	assert.Equal(t, []string{keptDir, addedDir}, c.Directories())
+	require.Eventually(t, func() bool {
+		sizes := gaugeValuesByPath(findMetricFamily(t, registry, "directory_size_bytes"))
+		_, hasKept := sizes[keptDir]
+		_, hasAdded := sizes[addedDir]
+		_, hasRemoved := sizes[removedDir]
+
+		return hasKept && hasAdded && !hasRemoved
+	}, time.Second, 10*time.Millisecond)
+
+	assert.NotContains(t, gaugeValuesByPath(findMetricFamily(t, registry, "directory_scan_success")), removedDir)
+}
+
+func TestDirectoryCollector_Readiness(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "a", "uploads"), 0o755))
+	missing := filepath.Join(root, "missing")
+
+	c := collector.NewDirectoryCollector(
+		collector.WithDirectories([]string{filepath.Join(root, "*", "uploads"), missing}),
+	)
+
+	readiness := c.Readiness()
+	assert.False(t, readiness.Ready())
+	assert.Equal(t, []string{filepath.Join(root, "*", "uploads"), missing}, readiness.Pending)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return c.Readiness().Ready()
+	}, time.Second, 10*time.Millisecond)
+
+	readiness = c.Readiness()
+	assert.Empty(t, readiness.Pending)
+	require.Len(t, readiness.Failing, 1)
+	assert.Equal(t, "missing", readiness.Failing[0].Name)
+	assert.Equal(t, missing, readiness.Failing[0].Path)
+	assert.Contains(t, readiness.Failing[0].Error, "no such file or directory")
+	assert.True(t, readiness.Failing[0].LastSuccess.IsZero())
+}
+
+func TestDirectoryCollector_SetTargets_BeforeStart(t *testing.T) {
+	c := collector.NewDirectoryCollector(
+		collector.WithDirectories([]string{"/tmp"}),
+	)
+
+	c.SetTargets([]config.Directory{{Path: "/var/tmp"}})
+
+	assert.Equal(t, []string{"/var/tmp"}, c.Directories())
+}
+
+func TestDirectoryCollector_Collect_WithGlobPattern_ExpandsOnEveryScan(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "a", "uploads"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "b", "uploads"), 0o755))
+
+	c := collector.NewDirectoryCollector(
+		collector.WithDirectories([]string{filepath.Join(root, "*", "uploads")}),
+		collector.WithScanInterval(50*time.Millisecond),
+	)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return len(gaugeValuesByPath(findMetricFamily(t, registry, "directory_size_bytes"))) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "a")))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "c", "uploads"), 0o755))
+
+	assert.Eventually(t, func() bool {
+		sizes := gaugeValuesByPath(findMetricFamily(t, registry, "directory_size_bytes"))
+		_, hasB := sizes[filepath.Join(root, "b", "uploads")]
+		_, hasC := sizes[filepath.Join(root, "c", "uploads")]
+
+		return len(sizes) == 2 && hasB && hasC
+	}, time.Second, 10*time.Millisecond)
+
+	names := make([]string, 0)
+	for _, metric := range findMetricFamily(t, registry, "directory_size_bytes").Metric {
+		for _, label := range metric.Label {
+			if label.GetName() == "name" {
+				names = append(names, label.GetValue())
+			}
+		}
+	}
+	assert.ElementsMatch(t, []string{"b/uploads", "c/uploads"}, names)
+}
+
+func TestDirectoryCollector_Collect_WithCaptureGroups_AddsLabels(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "acme", "data"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "globex", "data"), 0o755))
+
+	c := collector.NewDirectoryCollector(
+		collector.WithTargets([]config.Directory{
+			{
+				Path:   filepath.Join(root, "(?P<tenant>[^/]+)", "data"),
+				Labels: map[string]string{"team": "platform"},
+			},
+		}),
+	)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return len(gaugeValuesByPath(findMetricFamily(t, registry, "directory_size_bytes"))) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	tenants := make(map[string]string)
+	for _, metric := range findMetricFamily(t, registry, "directory_size_bytes").Metric {
+		labels := make(map[string]string)
+		for _, label := range metric.Label {
+			labels[label.GetName()] = label.GetValue()
+		}
+
+		assert.Equal(t, "platform", labels["team"])
+		tenants[labels["name"]] = labels["tenant"]
+	}
+
+	assert.Equal(t, map[string]string{"acme/data": "acme", "globex/data": "globex"}, tenants)
+}
+
+func TestDirectoryCollector_Collect_WithDepth_ReportsLargestSubdirectories(t *testing.T) {
+	root := t.TempDir()
+	for name, size := range map[string]int{"small": 10, "medium": 1000, "large": 100000} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, name), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, name, "file"), make([]byte, size), 0o600))
+	}
+
+	c := collector.NewDirectoryCollector(
+		collector.WithTargets([]config.Directory{
+			{Path: root, Name: "root", Depth: 1, MaxSubdirectories: 2},
+		}),
+	)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return findMetricFamily(t, registry, "directory_size_bytes") != nil
+	}, time.Second, 10*time.Millisecond)
+
+	parents := make(map[string]string)
+	for _, metric := range findMetricFamily(t, registry, "directory_size_bytes").Metric {
+		labels := make(map[string]string)
+		for _, label := range metric.Label {
+			labels[label.GetName()] = label.GetValue()
+		}
+		parents[labels["name"]] = labels["parent"]
+	}
+
+	assert.Equal(t, map[string]string{
+		"root":        "",
+		"root/large":  root,
+		"root/medium": root,
+	}, parents)
+
+	sizes := gaugeValuesByPath(findMetricFamily(t, registry, "directory_size_bytes"))
+	assert.Greater(t, sizes[filepath.Join(root, "large")], float64(100000))
+}
+
+func TestDirectoryCollector_Collect_WithTopEntries_ReportsLargestEntries(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "videos"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "videos", "movie.mp4"), make([]byte, 5000), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "notes.txt"), make([]byte, 10), 0o600))
+
+	c := collector.NewDirectoryCollector(
+		collector.WithTargets([]config.Directory{
+			{Path: root, Name: "media", TopEntries: 1},
+		}),
+	)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return findMetricFamily(t, registry, "directory_largest_entry_bytes") != nil
+	}, time.Second, 10*time.Millisecond)
+
+	entries := make(map[string]string)
+	for _, metric := range findMetricFamily(t, registry, "directory_largest_entry_bytes").Metric {
+		labels := make(map[string]string)
+		for _, label := range metric.Label {
+			labels[label.GetName()] = label.GetValue()
+		}
+
+		assert.Equal(t, "1", labels["rank"])
+		entries[labels["type"]] = labels["entry"]
+	}
+	assert.Equal(t, map[string]string{"file": "videos/movie.mp4", "directory": "videos"}, entries)
+
+	largest, err := c.LargestEntries("media")
+	require.NoError(t, err)
+	assert.Equal(t, root, largest.Path)
+	assert.Equal(t, []walker.Entry{{Path: "videos/movie.mp4", Size: 5000}}, largest.Files)
+	assert.Len(t, largest.Directories, 1)
+
+	_, err = c.LargestEntries("unknown")
+	assert.ErrorIs(t, err, collector.ErrDirectoryNotFound)
+}
+
+func TestDirectoryCollector_Collect_WithFileTypes_ReportsSizeByType(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "movie.mp4"), make([]byte, 5000), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "notes.txt"), make([]byte, 10), 0o600))
+
+	c := collector.NewDirectoryCollector(
+		collector.WithTargets([]config.Directory{
+			{Path: root, FileTypes: map[string][]string{"video": {".mp4"}, "logs": {".log"}}},
+		}),
+	)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return findMetricFamily(t, registry, "directory_size_by_type_bytes") != nil
+	}, time.Second, 10*time.Millisecond)
+
+	sizes := make(map[string]float64)
+	for _, metric := range findMetricFamily(t, registry, "directory_size_by_type_bytes").Metric {
+		for _, label := range metric.Label {
+			if label.GetName() == "type" {
+				sizes[label.GetValue()] = metric.Gauge.GetValue()
+			}
+		}
+	}
+	assert.Equal(t, map[string]float64{"video": 5000, "logs": 0, "other": 10}, sizes)
+}
+
+func TestDirectoryCollector_Collect_WithAgeBuckets_ReportsSizeByAge(t *testing.T) {
+	root := t.TempDir()
+	oldFile := filepath.Join(root, "old.bak")
+	require.NoError(t, os.WriteFile(oldFile, make([]byte, 200), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "new.bak"), make([]byte, 100), 0o600))
+
+	oldModTime := time.Now().Add(-10 * 24 * time.Hour)
+	require.NoError(t, os.Chtimes(oldFile, oldModTime, oldModTime))
+
+	c := collector.NewDirectoryCollector(
+		collector.WithTargets([]config.Directory{
+			{Path: root, AgeBuckets: []config.Duration{config.Duration(7 * 24 * time.Hour)}},
+		}),
+	)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return findMetricFamily(t, registry, "directory_bytes_by_age") != nil
+	}, time.Second, 10*time.Millisecond)
+
+	sizes := make(map[string]float64)
+	for _, metric := range findMetricFamily(t, registry, "directory_bytes_by_age").Metric {
+		for _, label := range metric.Label {
+			if label.GetName() == "le" {
+				sizes[label.GetValue()] = metric.Gauge.GetValue()
+			}
+		}
+	}
+	assert.Equal(t, map[string]float64{"604800": 100, "+Inf": 300}, sizes)
+
+	oldestMetric := findMetricFamily(t, registry, "directory_oldest_file_timestamp_seconds")
+	require.NotNil(t, oldestMetric)
+	assert.InDelta(t, float64(oldModTime.Unix()), oldestMetric.Metric[0].Gauge.GetValue(), 1)
+
+	newestMetric := findMetricFamily(t, registry, "directory_newest_file_timestamp_seconds")
+	require.NotNil(t, newestMetric)
+	assert.InDelta(t, float64(time.Now().Unix()), newestMetric.Metric[0].Gauge.GetValue(), 5)
+}
+
+func TestDirectoryCollector_Collect_WithQuota_ReportsStatus(t *testing.T) {
+	observedZapCore, observedLogs := observer.New(zap.InfoLevel)
+
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), make([]byte, 2000), 0o600))
+
+	c := collector.NewDirectoryCollector(
+		collector.WithLogger(zap.New(observedZapCore)),
+		collector.WithTargets([]config.Directory{
+			{Path: root, Quota: config.Quota{SoftBytes: 1000, HardBytes: 1000000, HardFiles: 10}},
+		}),
+	)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return findMetricFamily(t, registry, "directory_quota_status") != nil
+	}, time.Second, 10*time.Millisecond)
+
+	statusMetric := findMetricFamily(t, registry, "directory_quota_status")
+	assert.Equal(t, float64(1), statusMetric.Metric[0].Gauge.GetValue())
+
+	limits := make(map[string]float64)
+	for _, metric := range findMetricFamily(t, registry, "directory_quota_bytes").Metric {
+		for _, label := range metric.Label {
+			if label.GetName() == "limit" {
+				limits[label.GetValue()] = metric.Gauge.GetValue()
+			}
+		}
+	}
+	assert.Equal(t, map[string]float64{"soft": 1000, "hard": 1000000}, limits)
+
+	filesMetric := findMetricFamily(t, registry, "directory_quota_files")
+	require.NotNil(t, filesMetric)
+	assert.Len(t, filesMetric.Metric, 1)
+
+	crossed := observedLogs.FilterMessage("directory quota threshold crossed").All()
+	require.Len(t, crossed, 1)
+	assert.Equal(t, "soft", crossed[0].ContextMap()["status"])
+}
+
+// recordingNotifier records the alerts it receives
+type recordingNotifier struct {
+	mutex  sync.Mutex
+	alerts []notifier.Alert
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, alert notifier.Alert) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.alerts = append(n.alerts, alert)
+
+	return nil
+}
+
+// alertsOfType returns the received alerts of the given type
+func (n *recordingNotifier) alertsOfType(alertType string) []notifier.Alert {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	var alerts []notifier.Alert
+	for _, alert := range n.alerts {
+		if alert.Type == alertType {
+			alerts = append(alerts, alert)
+		}
+	}
+
+	return alerts
+}
+
+func TestDirectoryCollector_WithNotifier_SendsQuotaAndGrowthAlerts(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), make([]byte, 2000), 0o600))
+
+	n := &recordingNotifier{}
+	c := collector.NewDirectoryCollector(
+		collector.WithTargets([]config.Directory{{Path: root, Name: "data", Quota: config.Quota{SoftBytes: 1000}}}),
+		collector.WithScanInterval(50*time.Millisecond),
+		collector.WithNotifier(n),
+		collector.WithGrowthAlertPercent(50),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return len(n.alertsOfType(notifier.AlertTypeQuota)) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	quotaAlert := n.alertsOfType(notifier.AlertTypeQuota)[0]
+	assert.Equal(t, "data", quotaAlert.Name)
+	assert.Equal(t, "soft", quotaAlert.Status)
+	assert.Equal(t, "ok", quotaAlert.PreviousStatus)
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "b.txt"), make([]byte, 1024*1024), 0o600))
+
+	require.Eventually(t, func() bool {
+		return len(n.alertsOfType(notifier.AlertTypeGrowth)) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	growthAlert := n.alertsOfType(notifier.AlertTypeGrowth)[0]
+	assert.Greater(t, growthAlert.GrowthPercent, float64(50))
+	assert.Greater(t, growthAlert.SizeBytes, growthAlert.PreviousSizeBytes)
+	assert.Len(t, n.alertsOfType(notifier.AlertTypeQuota), 1)
+}
+
+// blockingNotifier blocks on every alert until the context is cancelled, like an unreachable webhook
+type blockingNotifier struct{}
+
+func (blockingNotifier) Notify(ctx context.Context, _ notifier.Alert) error {
+	<-ctx.Done()
+
+	return ctx.Err()
+}
+
+func TestDirectoryCollector_WithBlockingNotifier_DoesNotBlockScans(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), make([]byte, 2000), 0o600))
+
+	c := collector.NewDirectoryCollector(
+		collector.WithTargets([]config.Directory{{Path: root, Name: "data", Quota: config.Quota{SoftBytes: 1000}}}),
+		collector.WithScanInterval(time.Hour),
+		collector.WithNotifier(blockingNotifier{}),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return c.Readiness().Ready()
+	}, time.Second, 10*time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		_, err := c.Rescan("data")
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestDirectoryCollector_Collect_WithoutQuota_DoesNotReportStatus(t *testing.T) {
+	c := collector.NewDirectoryCollector(
+		collector.WithDirectories([]string{"./testdata/example_directory"}),
+	)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return findMetricFamily(t, registry, "directory_size_bytes") != nil
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Nil(t, findMetricFamily(t, registry, "directory_quota_status"))
+	assert.Nil(t, findMetricFamily(t, registry, "directory_quota_bytes"))
+}
+
+func TestDirectoryCollector_Collect_WithTopOwners_ReportsSizeByOwner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("owners are not supported on windows")
+	}
+
+	current, err := user.Current()
+	require.NoError(t, err)
+
+	c := collector.NewDirectoryCollector(
+		collector.WithTargets([]config.Directory{{Path: "./testdata/example_directory", TopOwners: 5}}),
+	)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return findMetricFamily(t, registry, "directory_size_by_owner_bytes") != nil
+	}, time.Second, 10*time.Millisecond)
+
+	ownerMetric := findMetricFamily(t, registry, "directory_size_by_owner_bytes")
+	require.Len(t, ownerMetric.Metric, 1)
+	for _, label := range ownerMetric.Metric[0].Label {
+		if label.GetName() == "user" {
+			assert.Equal(t, current.Username, label.GetValue())
+		}
+	}
+
+	sizeMetric := findMetricFamily(t, registry, "directory_size_bytes")
+	assert.Equal(t, sizeMetric.Metric[0].Gauge.GetValue(), ownerMetric.Metric[0].Gauge.GetValue())
+	assert.NotNil(t, findMetricFamily(t, registry, "directory_size_by_group_bytes"))
+}
+
+func TestDirectoryCollector_Collect_WithSizeMode(t *testing.T) {
+	scenarios := []struct {
+		sizeMode          string
+		expectedSize      bool
+		expectedDiskUsage bool
+	}{
+		{sizeMode: config.SizeModeApparent, expectedSize: true, expectedDiskUsage: false},
+		{sizeMode: config.SizeModeAllocated, expectedSize: false, expectedDiskUsage: true},
+		{sizeMode: config.SizeModeBoth, expectedSize: true, expectedDiskUsage: true},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.sizeMode, func(t *testing.T) {
+			c := collector.NewDirectoryCollector(
+				collector.WithTargets([]config.Directory{
+					{Path: "./testdata/example_directory", SizeMode: scenario.sizeMode},
+				}),
+			)
+
+			registry := prometheus.NewRegistry()
+			registry.MustRegister(c)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+
+			c.Start(ctx)
+
+			require.Eventually(t, func() bool {
+				return findMetricFamily(t, registry, "directory_last_scan_timestamp_seconds") != nil
+			}, time.Second, 10*time.Millisecond)
+
+			assert.Equal(t, scenario.expectedSize, findMetricFamily(t, registry, "directory_size_bytes") != nil)
+			assert.Equal(t, scenario.expectedDiskUsage, findMetricFamily(t, registry, "directory_disk_usage_bytes") != nil)
+		})
+	}
+}
+
+func TestDirectoryCollector_Collect_WithExclude_ReportsExcludedBytes(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), make([]byte, 100), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "b.tmp"), make([]byte, 200), 0o600))
+
+	c := collector.NewDirectoryCollector(
+		collector.WithTargets([]config.Directory{{Path: root, Exclude: []string{"*.tmp"}}}),
+	)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return findMetricFamily(t, registry, "directory_excluded_bytes") != nil
+	}, time.Second, 10*time.Millisecond)
+
+	excludedMetric := findMetricFamily(t, registry, "directory_excluded_bytes")
+	assert.Equal(t, float64(200), excludedMetric.Metric[0].Gauge.GetValue())
+
+	filesMetric := findMetricFamily(t, registry, "directory_files_total")
+	require.NotNil(t, filesMetric)
+	assert.Equal(t, float64(1), filesMetric.Metric[0].Gauge.GetValue())
+}
+
+func TestDirectoryCollector_Rescan(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file"), make([]byte, 100), 0o600))
+
+	c := collector.NewDirectoryCollector(
+		collector.WithTargets([]config.Directory{{Path: dir, Name: "media"}}),
+		collector.WithScanInterval(time.Hour),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return c.Readiness().Ready()
+	}, time.Second, 10*time.Millisecond)
+
+	before, err := c.Summary("media")
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other"), make([]byte, 50), 0o600))
+
+	var done <-chan struct{}
+	require.Eventually(t, func() bool {
+		var err error
+		done, err = c.Rescan("media")
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("rescan did not finish")
+	}
+
+	summary, err := c.Summary("media")
+	require.NoError(t, err)
+	assert.Equal(t, before.Size+50, summary.Size)
+	assert.Equal(t, int64(2), summary.Files)
+	assert.True(t, summary.LastScan.After(before.LastScan))
+
+	_, err = c.Rescan("unknown")
+	assert.ErrorIs(t, err, collector.ErrDirectoryNotFound)
+}
+
+func TestDirectoryCollector_RescanAll(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "a", "uploads"), 0o755))
+	media := t.TempDir()
+
+	pattern := filepath.Join(root, "*", "uploads")
+	c := collector.NewDirectoryCollector(
+		collector.WithDirectories([]string{pattern, media}),
+		collector.WithScanInterval(time.Hour),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return c.Readiness().Ready()
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "b", "uploads"), 0o755))
+
+	var (
+		done   <-chan struct{}
+		queued []string
+	)
+	require.Eventually(t, func() bool {
+		done, queued, _ = c.RescanAll()
+		return len(queued) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	assert.ElementsMatch(t, []string{pattern, media}, queued)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("rescan did not finish")
+	}
+
+	_, err := c.Summary("b/uploads")
+	assert.NoError(t, err)
+}
+
+func TestDirectoryCollector_WithAmbiguousName_ReturnsError(t *testing.T) {
+	srv, backups := t.TempDir(), t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(srv, "a", "data"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(backups, "a", "data"), 0o755))
+
+	c := collector.NewDirectoryCollector(
+		collector.WithDirectories([]string{filepath.Join(srv, "*", "data"), filepath.Join(backups, "*", "data")}),
+		collector.WithScanInterval(time.Hour),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return c.Readiness().Ready()
+	}, time.Second, 10*time.Millisecond)
+
+	expected := []string{filepath.Join(srv, "a", "data"), filepath.Join(backups, "a", "data")}
+	sort.Strings(expected)
+
+	var ambiguous *collector.AmbiguousNameError
+
+	_, err := c.Summary("a/data")
+	require.ErrorAs(t, err, &ambiguous)
+	assert.Equal(t, expected, ambiguous.Paths)
+
+	_, err = c.LargestEntries("a/data")
+	assert.ErrorAs(t, err, &ambiguous)
+
+	_, err = c.Rescan("a/data")
+	assert.ErrorAs(t, err, &ambiguous)
 }
@@ -0,0 +1,64 @@
+package collector
+
+import "time"
+
+// sizeSample is the size of a directory at a point in time
+type sizeSample struct {
+	at   time.Time
+	size int64
+}
+
+// growthWindow keeps the size samples of a directory within a rolling time window, to estimate how fast it grows
+type growthWindow struct {
+	window  time.Duration
+	samples []sizeSample
+}
+
+// newGrowthWindow creates a new growthWindow that keeps the samples of the given duration
+func newGrowthWindow(window time.Duration) *growthWindow {
+	return &growthWindow{window: window}
+}
+
+// minSamples is the number of samples always kept, even outside the window, so the rate of directories scanned
+// less often than the window is still calculated from their last two scans
+const minSamples = 2
+
+// add adds a sample and drops the ones that fell out of the window, keeping at least the previous one
+func (g *growthWindow) add(at time.Time, size int64) {
+	g.samples = append(g.samples, sizeSample{at: at, size: size})
+
+	first := 0
+	for len(g.samples)-first > minSamples && at.Sub(g.samples[first].at) > g.window {
+		first++
+	}
+	g.samples = g.samples[first:]
+}
+
+// rate returns the growth rate in bytes per second, calculated with a least squares linear regression over the
+// samples, like the Prometheus deriv function. It returns false when there are not enough samples.
+func (g *growthWindow) rate() (float64, bool) {
+	if len(g.samples) < 2 {
+		return 0, false
+	}
+
+	// Times are relative to the first sample, to keep the values small
+	start := g.samples[0].at
+	var sumX, sumY, sumXY, sumX2 float64
+	for _, sample := range g.samples {
+		x := sample.at.Sub(start).Seconds()
+		y := float64(sample.size)
+
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumX2 += x * x
+	}
+
+	n := float64(len(g.samples))
+	denominator := n*sumX2 - sumX*sumX
+	if denominator == 0 {
+		return 0, false
+	}
+
+	return (n*sumXY - sumX*sumY) / denominator, true
+}
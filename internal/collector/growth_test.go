@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGrowthWindow_Rate(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now()
+	g := newGrowthWindow(time.Hour)
+
+	_, ok := g.rate()
+	assert.False(t, ok)
+
+	g.add(start, 1000)
+	_, ok = g.rate()
+	assert.False(t, ok)
+
+	g.add(start.Add(10*time.Second), 2000)
+	g.add(start.Add(20*time.Second), 3000)
+
+	rate, ok := g.rate()
+	assert.True(t, ok)
+	assert.InDelta(t, 100, rate, 0.001)
+}
+
+func TestGrowthWindow_DropsSamplesOutsideTheWindow(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now()
+	g := newGrowthWindow(time.Minute)
+
+	g.add(start, 0)
+	g.add(start.Add(time.Minute), 6000)
+	g.add(start.Add(2*time.Minute), 6000)
+
+	rate, ok := g.rate()
+	assert.True(t, ok)
+	assert.InDelta(t, 0, rate, 0.001)
+	assert.Len(t, g.samples, 2)
+}
+
+func TestGrowthWindow_WithScansLessOftenThanTheWindow_KeepsThePreviousSample(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now()
+	g := newGrowthWindow(time.Hour)
+
+	g.add(start, 0)
+	g.add(start.Add(2*time.Hour), 7200)
+	g.add(start.Add(4*time.Hour), 21600)
+
+	rate, ok := g.rate()
+	assert.True(t, ok)
+	assert.InDelta(t, 2, rate, 0.001)
+	assert.Len(t, g.samples, 2)
+}
@@ -0,0 +1,58 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/brpaz/prom-dirsize-exporter/internal/glob"
+)
+
+// ErrDirectoryNotFound is returned when there's no directory with the requested name, or it was not scanned yet
+var ErrDirectoryNotFound = errors.New("directory not found")
+
+// AmbiguousNameError is returned when more than one directory has the requested name, like the matches of
+// two glob patterns with the same relative path
+type AmbiguousNameError struct {
+	Name string
+	// Paths holds the sorted paths of the directories with the name
+	Paths []string
+}
+
+func (e *AmbiguousNameError) Error() string {
+	return fmt.Sprintf("directory name %q is ambiguous, it is used by %s", e.Name, strings.Join(e.Paths, ", "))
+}
+
+// lookup returns the path of the directory with the given name. Static directories are found even before
+// they are scanned, while the names of glob pattern matches are only known once they are scanned.
+// Must be called with the mutex locked.
+func (c *DirectoryCollector) lookup(name string) (string, error) {
+	seen := make(map[string]struct{})
+	for path, s := range c.schedules {
+		if !glob.IsPattern(path) && s.target.LabelName() == name {
+			seen[path] = struct{}{}
+		}
+	}
+
+	for path, state := range c.states {
+		if state.name == name && glob.IsPattern(state.target.Path) {
+			seen[path] = struct{}{}
+		}
+	}
+
+	paths := make([]string, 0, len(seen))
+	for path := range seen {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	switch len(paths) {
+	case 0:
+		return "", ErrDirectoryNotFound
+	case 1:
+		return paths[0], nil
+	default:
+		return "", &AmbiguousNameError{Name: name, Paths: paths}
+	}
+}
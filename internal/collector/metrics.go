@@ -0,0 +1,202 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metricDescs holds the descriptors of all the metrics exported for a directory
+type metricDescs struct {
+	size            *prometheus.Desc
+	diskUsage       *prometheus.Desc
+	files           *prometheus.Desc
+	directories     *prometheus.Desc
+	symlinks        *prometheus.Desc
+	excluded        *prometheus.Desc
+	sizeByType      *prometheus.Desc
+	sizeByAge       *prometheus.Desc
+	sizeByUser      *prometheus.Desc
+	sizeByGroup     *prometheus.Desc
+	oldestFile      *prometheus.Desc
+	newestFile      *prometheus.Desc
+	growthRate      *prometheus.Desc
+	timeUntilFull   *prometheus.Desc
+	filesystemSize  *prometheus.Desc
+	filesystemFree  *prometheus.Desc
+	filesystemUsage *prometheus.Desc
+	quotaBytes      *prometheus.Desc
+	quotaFiles      *prometheus.Desc
+	quotaStatus     *prometheus.Desc
+	lastScan        *prometheus.Desc
+	scanSuccess     *prometheus.Desc
+	scanDuration    *prometheus.Desc
+	scanErrors      *prometheus.Desc
+	largestEntry    *prometheus.Desc
+}
+
+// newMetricDescs creates the metric descriptors, with the given extra labels added to all of them.
+// The metric names are built from the namespace, the optional prefix and the metric name, like
+// "directory_size_bytes" or "directory_logs_size_bytes".
+func newMetricDescs(namespace string, prefix string, constLabels prometheus.Labels) *metricDescs {
+	labels := []string{"name", "path"}
+
+	return &metricDescs{
+		// Subdirectories of the breakdown are reported in the same metric, with their root directory in
+		// the "parent" label. It's empty for the configured directories, which Prometheus treats as unset.
+		size: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, prefix, CollectorName),
+			"Size of the directory in bytes.",
+			append(labels, "parent"), constLabels,
+		),
+		diskUsage: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, prefix, "disk_usage_bytes"),
+			"Space allocated on disk for the directory in bytes.",
+			labels, constLabels,
+		),
+		files: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, prefix, "files_total"),
+			"Number of regular files in the directory, counting hard links to the same file once.",
+			labels, constLabels,
+		),
+		directories: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, prefix, "subdirectories_total"),
+			"Number of subdirectories in the directory, at any depth.",
+			labels, constLabels,
+		),
+		symlinks: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, prefix, "symlinks_total"),
+			"Number of symbolic links in the directory.",
+			labels, constLabels,
+		),
+		excluded: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, prefix, "excluded_bytes"),
+			"Size in bytes of the entries of the directory left out by the include and exclude patterns.",
+			labels, constLabels,
+		),
+		sizeByType: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, prefix, "size_by_type_bytes"),
+			"Size in bytes of the regular files of the directory, by file type.",
+			append(labels, "type"), constLabels,
+		),
+		sizeByUser: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, prefix, "size_by_owner_bytes"),
+			"Size in bytes of the entries of the directory owned by each of its largest users.",
+			append(labels, "user"), constLabels,
+		),
+		sizeByGroup: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, prefix, "size_by_group_bytes"),
+			"Size in bytes of the entries of the directory owned by each of its largest groups.",
+			append(labels, "group"), constLabels,
+		),
+		// The size by age is reported as cumulative buckets, like a Prometheus histogram, with the maximum
+		// age in seconds in the "le" label
+		sizeByAge: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, prefix, "bytes_by_age"),
+			"Size in bytes of the regular files of the directory modified within the given number of seconds.",
+			append(labels, "le"), constLabels,
+		),
+		oldestFile: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, prefix, "oldest_file_timestamp_seconds"),
+			"Unix timestamp of the modification time of the oldest file in the directory.",
+			labels, constLabels,
+		),
+		newestFile: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, prefix, "newest_file_timestamp_seconds"),
+			"Unix timestamp of the modification time of the newest file in the directory.",
+			labels, constLabels,
+		),
+		growthRate: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, prefix, "growth_bytes_per_second"),
+			"Growth rate of the directory size in bytes per second, over the recent scans.",
+			labels, constLabels,
+		),
+		timeUntilFull: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, prefix, "estimated_seconds_until_full"),
+			"Estimated time in seconds until the filesystem of the directory is full, at the current growth rate.",
+			labels, constLabels,
+		),
+		filesystemSize: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, prefix, "filesystem_size_bytes"),
+			"Total size in bytes of the filesystem where the directory is stored.",
+			append(labels, "mountpoint", "fstype"), constLabels,
+		),
+		filesystemFree: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, prefix, "filesystem_free_bytes"),
+			"Free space in bytes, available to unprivileged users, of the filesystem where the directory is stored.",
+			append(labels, "mountpoint", "fstype"), constLabels,
+		),
+		filesystemUsage: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, prefix, "filesystem_usage_ratio"),
+			"Size of the directory over the total size of its filesystem.",
+			append(labels, "mountpoint", "fstype"), constLabels,
+		),
+		quotaBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, prefix, "quota_bytes"),
+			"Size limit in bytes of the directory quota, by limit.",
+			append(labels, "limit"), constLabels,
+		),
+		quotaFiles: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, prefix, "quota_files"),
+			"Number of files limit of the directory quota, by limit.",
+			append(labels, "limit"), constLabels,
+		),
+		quotaStatus: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, prefix, "quota_status"),
+			"Status of the directory against its quota: 0 when within the limits, 1 over the soft limit and 2 over the hard limit.",
+			labels, constLabels,
+		),
+		lastScan: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, prefix, "last_scan_timestamp_seconds"),
+			"Unix timestamp of the last completed scan of the directory.",
+			labels, constLabels,
+		),
+		scanSuccess: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, prefix, "scan_success"),
+			"Whether the last scan of the directory was successful (1) or not (0).",
+			labels, constLabels,
+		),
+		scanDuration: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, prefix, "scan_duration_seconds"),
+			"Duration of the last scan of the directory in seconds.",
+			labels, constLabels,
+		),
+		scanErrors: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, prefix, "scan_errors_total"),
+			"Total number of errors found while scanning the directory, by reason.",
+			append(labels, "reason"), constLabels,
+		),
+		largestEntry: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, prefix, "largest_entry_bytes"),
+			"Size in bytes of the largest files and subdirectories of the directory, by rank.",
+			append(labels, "rank", "entry", "type"), constLabels,
+		),
+	}
+}
+
+// describe sends all the descriptors to the channel
+func (d *metricDescs) describe(ch chan<- *prometheus.Desc) {
+	ch <- d.size
+	ch <- d.diskUsage
+	ch <- d.files
+	ch <- d.directories
+	ch <- d.symlinks
+	ch <- d.excluded
+	ch <- d.sizeByType
+	ch <- d.sizeByAge
+	ch <- d.sizeByUser
+	ch <- d.sizeByGroup
+	ch <- d.oldestFile
+	ch <- d.newestFile
+	ch <- d.growthRate
+	ch <- d.timeUntilFull
+	ch <- d.filesystemSize
+	ch <- d.filesystemFree
+	ch <- d.filesystemUsage
+	ch <- d.quotaBytes
+	ch <- d.quotaFiles
+	ch <- d.quotaStatus
+	ch <- d.lastScan
+	ch <- d.scanSuccess
+	ch <- d.scanDuration
+	ch <- d.scanErrors
+	ch <- d.largestEntry
+}
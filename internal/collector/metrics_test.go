@@ -0,0 +1,53 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/prom-dirsize-exporter/internal/config"
+)
+
+// variableLabels returns the names of the variable labels of the descriptor, finding out how many it has from
+// the values that NewConstMetric accepts
+func variableLabels(t *testing.T, desc *prometheus.Desc) []string {
+	t.Helper()
+
+	for count := 0; count <= 10; count++ {
+		metric, err := prometheus.NewConstMetric(desc, prometheus.GaugeValue, 0, make([]string, count)...)
+		if err != nil {
+			continue
+		}
+
+		var m dto.Metric
+		require.NoError(t, metric.Write(&m))
+
+		names := make([]string, 0, len(m.GetLabel()))
+		for _, label := range m.GetLabel() {
+			names = append(names, label.GetName())
+		}
+
+		return names
+	}
+
+	t.Fatalf("no label count accepted by %s", desc)
+
+	return nil
+}
+
+func TestMetricDescs_VariableLabelsAreReserved(t *testing.T) {
+	t.Parallel()
+
+	ch := make(chan *prometheus.Desc, 100)
+	newMetricDescs(CollectorNamespace, "", nil).describe(ch)
+	close(ch)
+
+	for desc := range ch {
+		for _, name := range variableLabels(t, desc) {
+			assert.Contains(t, config.ReservedLabels, name, "label of %s", desc)
+		}
+	}
+}
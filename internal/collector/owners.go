@@ -0,0 +1,113 @@
+package collector
+
+import (
+	"os/user"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// othersOwner is the owner name of the entries of the owners that are not in the top of the breakdown
+const othersOwner = "others"
+
+// ownerSize holds the size of the entries owned by a user or group
+type ownerSize struct {
+	name string
+	size int64
+}
+
+// ownerNames resolves user and group IDs to their names, caching the results, as lookups might query
+// external services like LDAP. IDs without a name are resolved to the ID itself.
+type ownerNames struct {
+	mutex  sync.Mutex
+	users  map[uint32]string
+	groups map[uint32]string
+}
+
+// newOwnerNames creates a new empty ownerNames cache
+func newOwnerNames() *ownerNames {
+	return &ownerNames{
+		users:  make(map[uint32]string),
+		groups: make(map[uint32]string),
+	}
+}
+
+// user returns the name of the user with the given ID
+func (n *ownerNames) user(uid uint32) string {
+	return n.resolve(n.users, uid, func(id string) (string, error) {
+		u, err := user.LookupId(id)
+		if err != nil {
+			return "", err
+		}
+
+		return u.Username, nil
+	})
+}
+
+// group returns the name of the group with the given ID
+func (n *ownerNames) group(gid uint32) string {
+	return n.resolve(n.groups, gid, func(id string) (string, error) {
+		g, err := user.LookupGroupId(id)
+		if err != nil {
+			return "", err
+		}
+
+		return g.Name, nil
+	})
+}
+
+// resolve returns the cached name of the ID, looking it up when it's not cached yet
+func (n *ownerNames) resolve(cache map[uint32]string, id uint32, lookup func(string) (string, error)) string {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if name, ok := cache[id]; ok {
+		return name
+	}
+
+	name, err := lookup(strconv.FormatUint(uint64(id), 10))
+	if err != nil || name == "" {
+		name = strconv.FormatUint(uint64(id), 10)
+	}
+	cache[id] = name
+
+	return name
+}
+
+// topOwners returns the limit largest owners, sorted from the largest to the smallest, with the sizes of the
+// rest added to an othersOwner entry at the end. IDs that resolve to the same name are merged, as they would
+// be reported in the same series, and owners named like othersOwner are reported by their ID.
+func topOwners(sizes map[uint32]int64, limit int, resolve func(uint32) string) []ownerSize {
+	sizesByName := make(map[string]int64, len(sizes))
+	for id, size := range sizes {
+		name := resolve(id)
+		if name == othersOwner {
+			name = strconv.FormatUint(uint64(id), 10)
+		}
+		sizesByName[name] += size
+	}
+
+	owners := make([]ownerSize, 0, len(sizesByName))
+	for name, size := range sizesByName {
+		owners = append(owners, ownerSize{name: name, size: size})
+	}
+
+	sort.Slice(owners, func(i, j int) bool {
+		if owners[i].size != owners[j].size {
+			return owners[i].size > owners[j].size
+		}
+
+		return owners[i].name < owners[j].name
+	})
+
+	if len(owners) <= limit {
+		return owners
+	}
+
+	others := ownerSize{name: othersOwner}
+	for _, owner := range owners[limit:] {
+		others.size += owner.size
+	}
+
+	return append(owners[:limit], others)
+}
@@ -0,0 +1,58 @@
+package collector
+
+import (
+	"os"
+	"os/user"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopOwners(t *testing.T) {
+	t.Parallel()
+
+	sizes := map[uint32]int64{1000: 500, 1001: 300, 1002: 100, 1003: 50}
+	resolve := func(id uint32) string { return "user" + strconv.Itoa(int(id)) }
+
+	assert.Equal(t, []ownerSize{
+		{name: "user1000", size: 500},
+		{name: "user1001", size: 300},
+		{name: othersOwner, size: 150},
+	}, topOwners(sizes, 2, resolve))
+
+	assert.Len(t, topOwners(sizes, 10, resolve), 4)
+}
+
+func TestTopOwners_WithConflictingNames(t *testing.T) {
+	t.Parallel()
+
+	sizes := map[uint32]int64{1000: 500, 1001: 300, 1002: 100, 1003: 50, 1004: 25}
+	names := map[uint32]string{1000: "alice", 1001: "alice", 1002: othersOwner, 1003: "bob", 1004: "carol"}
+	resolve := func(id uint32) string { return names[id] }
+
+	assert.Equal(t, []ownerSize{
+		{name: "alice", size: 800},
+		{name: "1002", size: 100},
+		{name: othersOwner, size: 75},
+	}, topOwners(sizes, 2, resolve))
+}
+
+func TestOwnerNames(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("owners are not supported on windows")
+	}
+
+	current, err := user.Current()
+	require.NoError(t, err)
+
+	names := newOwnerNames()
+
+	assert.Equal(t, current.Username, names.user(uint32(os.Getuid())))
+	assert.Equal(t, "4294967290", names.user(4294967290))
+	assert.Equal(t, "4294967290", names.group(4294967290))
+}
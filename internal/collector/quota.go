@@ -0,0 +1,81 @@
+package collector
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/brpaz/prom-dirsize-exporter/internal/config"
+)
+
+// quotaStatus is the status of a directory against its quota
+type quotaStatus int
+
+// Quota statuses, in increasing order of severity. The values are the ones reported in the status metric.
+const (
+	quotaStatusOK quotaStatus = iota
+	quotaStatusSoft
+	quotaStatusHard
+)
+
+// String returns the name of the quota status
+func (s quotaStatus) String() string {
+	switch s {
+	case quotaStatusSoft:
+		return "soft"
+	case quotaStatusHard:
+		return "hard"
+	default:
+		return "ok"
+	}
+}
+
+// evaluateQuota returns the status of a directory with the given size and number of files against the quota.
+// The most severe status of the size and the files is returned.
+func evaluateQuota(quota config.Quota, size int64, files int64) quotaStatus {
+	return max(
+		limitStatus(size, quota.SoftBytes, quota.HardBytes),
+		limitStatus(files, quota.SoftFiles, quota.HardFiles),
+	)
+}
+
+// limitStatus returns the status of the value against the soft and hard limits, ignoring the ones that are not set
+func limitStatus(value int64, soft int64, hard int64) quotaStatus {
+	switch {
+	case hard > 0 && value > hard:
+		return quotaStatusHard
+	case soft > 0 && value > soft:
+		return quotaStatusSoft
+	default:
+		return quotaStatusOK
+	}
+}
+
+// updateQuotaStatus evaluates the quota of the directory after a scan, logging when its status changes.
+// It returns the previous status and whether it changed. Must be called with the mutex locked.
+func (c *DirectoryCollector) updateQuotaStatus(directory string, state *directoryState) (quotaStatus, bool) {
+	quota := state.target.Quota
+	if !quota.Enabled() {
+		return quotaStatusOK, false
+	}
+
+	previous := state.quotaStatus
+	state.quotaStatus = evaluateQuota(quota, state.trackedSize(), state.counts.files)
+	if state.quotaStatus == previous {
+		return previous, false
+	}
+
+	fields := []zap.Field{
+		zap.String("directory", directory),
+		zap.String("status", state.quotaStatus.String()),
+		zap.String("previous_status", previous.String()),
+		zap.Int64("size", state.trackedSize()),
+		zap.Int64("files", state.counts.files),
+	}
+
+	if state.quotaStatus > previous {
+		c.logger.Warn("directory quota threshold crossed", fields...)
+	} else {
+		c.logger.Info("directory quota status recovered", fields...)
+	}
+
+	return previous, true
+}
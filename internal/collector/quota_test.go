@@ -0,0 +1,36 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/brpaz/prom-dirsize-exporter/internal/config"
+)
+
+func TestEvaluateQuota(t *testing.T) {
+	t.Parallel()
+
+	scenarios := []struct {
+		name     string
+		quota    config.Quota
+		size     int64
+		files    int64
+		expected quotaStatus
+	}{
+		{name: "Within limits", quota: config.Quota{SoftBytes: 100, HardBytes: 200}, size: 100, expected: quotaStatusOK},
+		{name: "Over soft bytes", quota: config.Quota{SoftBytes: 100, HardBytes: 200}, size: 150, expected: quotaStatusSoft},
+		{name: "Over hard bytes", quota: config.Quota{SoftBytes: 100, HardBytes: 200}, size: 250, expected: quotaStatusHard},
+		{name: "Only hard bytes", quota: config.Quota{HardBytes: 200}, size: 150, expected: quotaStatusOK},
+		{name: "Over soft files", quota: config.Quota{HardBytes: 200, SoftFiles: 10}, size: 150, files: 11, expected: quotaStatusSoft},
+		{name: "Most severe wins", quota: config.Quota{HardBytes: 200, SoftFiles: 10}, size: 250, files: 11, expected: quotaStatusHard},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, scenario.expected, evaluateQuota(scenario.quota, scenario.size, scenario.files))
+		})
+	}
+}
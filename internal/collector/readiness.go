@@ -0,0 +1,66 @@
+package collector
+
+import (
+	"sort"
+	"time"
+)
+
+// DirectoryStatus holds the status of the last scan of a directory that failed
+type DirectoryStatus struct {
+	Name string
+	Path string
+	// Error is the reason of the failure of the last scan
+	Error string
+	// LastSuccess is the time of the last successful scan, zero when the directory was never scanned successfully
+	LastSuccess time.Time
+}
+
+// Readiness holds the progress of the scans of the monitored directories
+type Readiness struct {
+	// Pending holds the paths of the directories whose first scan has not finished yet. A glob pattern is only
+	// scanned once all its matches are.
+	Pending []string
+	// Failing holds the directories whose last scan failed, sorted by path
+	Failing []DirectoryStatus
+}
+
+// Ready returns true when all the directories were scanned at least once
+func (r Readiness) Ready() bool {
+	return len(r.Pending) == 0
+}
+
+// Readiness returns the progress of the scans of the monitored directories.
+// Directories are pending until the scheduler is started and their first scan finishes, whether it succeeds or not.
+func (c *DirectoryCollector) Readiness() Readiness {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	readiness := Readiness{
+		Pending: make([]string, 0),
+		Failing: make([]DirectoryStatus, 0),
+	}
+
+	for _, target := range c.targets {
+		if s, ok := c.schedules[target.Path]; !ok || !s.scanned {
+			readiness.Pending = append(readiness.Pending, target.Path)
+		}
+	}
+
+	for path, state := range c.states {
+		if state.success {
+			continue
+		}
+
+		status := DirectoryStatus{Name: state.name, Path: path, LastSuccess: state.lastScan}
+		if state.scanError != nil {
+			status.Error = state.scanError.Error()
+		}
+		readiness.Failing = append(readiness.Failing, status)
+	}
+
+	sort.Slice(readiness.Failing, func(i, j int) bool {
+		return readiness.Failing[i].Path < readiness.Failing[j].Path
+	})
+
+	return readiness
+}
@@ -0,0 +1,99 @@
+package collector
+
+import (
+	"errors"
+	"sort"
+)
+
+// ErrScanInProgress is returned when a rescan is requested for a directory that is being scanned
+var ErrScanInProgress = errors.New("scan already in progress")
+
+// Rescan requests an immediate scan of the directory with the given name, and returns a channel that is closed
+// when the scan finishes. A directory matched by a glob pattern is scanned along with all the other matches of
+// the pattern. It fails with ErrDirectoryNotFound if there's no directory with the name, an AmbiguousNameError
+// if there are several, and ErrScanInProgress if the directory is already being scanned.
+func (c *DirectoryCollector) Rescan(name string) (<-chan struct{}, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	s, err := c.scheduleFor(name)
+	if err != nil {
+		return nil, err
+	}
+
+	done, ok := s.requestRescan()
+	if !ok {
+		return nil, ErrScanInProgress
+	}
+
+	return done, nil
+}
+
+// RescanAll requests an immediate scan of all the directories, and returns a channel that is closed when all
+// the scans finish. Directories that are already being scanned are skipped. The paths of the directories,
+// or glob patterns, that were queued and skipped are returned sorted.
+func (c *DirectoryCollector) RescanAll() (done <-chan struct{}, queued []string, skipped []string) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	queued = make([]string, 0, len(c.schedules))
+	skipped = make([]string, 0)
+	pending := make([]chan struct{}, 0, len(c.schedules))
+
+	for path, s := range c.schedules {
+		scanDone, ok := s.requestRescan()
+		if !ok {
+			skipped = append(skipped, path)
+			continue
+		}
+
+		queued = append(queued, path)
+		pending = append(pending, scanDone)
+	}
+
+	sort.Strings(queued)
+	sort.Strings(skipped)
+
+	allDone := make(chan struct{})
+	go func() {
+		for _, scanDone := range pending {
+			<-scanDone
+		}
+		close(allDone)
+	}()
+
+	return allDone, queued, skipped
+}
+
+// scheduleFor returns the schedule that scans the directory with the given name. Must be called with the mutex locked.
+func (c *DirectoryCollector) scheduleFor(name string) (*schedule, error) {
+	path, err := c.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Glob pattern matches are scanned by the schedule of their pattern
+	if state, ok := c.states[path]; ok {
+		path = state.target.Path
+	}
+
+	s, ok := c.schedules[path]
+	if !ok {
+		return nil, ErrDirectoryNotFound
+	}
+
+	return s, nil
+}
+
+// requestRescan asks the schedule to scan its target right away. It returns false if the target is being
+// scanned, as the schedule only accepts requests while it's waiting for the next scan.
+func (s *schedule) requestRescan() (chan struct{}, bool) {
+	done := make(chan struct{})
+
+	select {
+	case s.rescan <- done:
+		return done, true
+	default:
+		return nil, false
+	}
+}
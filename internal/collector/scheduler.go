@@ -0,0 +1,257 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"reflect"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/brpaz/prom-dirsize-exporter/internal/config"
+	"github.com/brpaz/prom-dirsize-exporter/internal/filesystem"
+	"github.com/brpaz/prom-dirsize-exporter/internal/glob"
+	"github.com/brpaz/prom-dirsize-exporter/internal/notifier"
+)
+
+// schedule holds a running scan loop of a target
+type schedule struct {
+	target config.Directory
+	cancel context.CancelFunc
+	// scanned is set once the first scan of all the directories of the target has finished
+	scanned bool
+	// rescan receives the on-demand scan requests, with a channel to close when the scan finishes.
+	// It's unbuffered, so requests are only accepted while the target is not being scanned.
+	rescan chan chan struct{}
+}
+
+// Start starts the background scheduler, that scans each directory right away and then on every scan interval.
+// Each directory has its own schedule, so a slow directory does not delay the others. The scheduler runs
+// until the context is cancelled.
+func (c *DirectoryCollector) Start(ctx context.Context) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.logger.Info("starting scan scheduler",
+		zap.Strings("directories", c.directories()),
+		zap.Duration("interval", c.scanInterval),
+	)
+
+	c.ctx = ctx
+	// Alerts are sent by their own goroutine, so the webhook retries don't delay the scans
+	if c.notifier != nil {
+		c.alerts = make(chan notifier.Alert, alertQueueSize)
+		go c.runNotifier(ctx, c.alerts)
+	}
+
+	for _, target := range c.targets {
+		c.startSchedule(target)
+	}
+}
+
+// SetTargets replaces the directories being monitored.
+// Directories that were removed stop being scanned and their series are dropped, new directories are scanned
+// right away and directories whose settings changed are rescheduled. Unchanged directories keep their state.
+func (c *DirectoryCollector) SetTargets(targets []config.Directory) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	wanted := make(map[string]config.Directory, len(targets))
+	for _, target := range targets {
+		wanted[target.Path] = target
+	}
+
+	for path, s := range c.schedules {
+		if target, ok := wanted[path]; ok && reflect.DeepEqual(target, s.target) {
+			continue
+		}
+
+		c.logger.Info("removing directory from scheduler", zap.String("directory", path))
+		s.cancel()
+		delete(c.schedules, path)
+	}
+
+	for path, state := range c.states {
+		if _, ok := c.schedules[state.target.Path]; !ok {
+			delete(c.states, path)
+		}
+	}
+
+	c.targets = targets
+
+	if c.ctx == nil {
+		return
+	}
+
+	for _, target := range targets {
+		if _, ok := c.schedules[target.Path]; !ok {
+			c.logger.Info("adding directory to scheduler", zap.String("directory", target.Path))
+			c.startSchedule(target)
+		}
+	}
+}
+
+// startSchedule starts the scan loop of a target. Must be called with the mutex locked.
+func (c *DirectoryCollector) startSchedule(target config.Directory) {
+	ctx, cancel := context.WithCancel(c.ctx)
+	s := &schedule{target: target, cancel: cancel, rescan: make(chan chan struct{})}
+	c.schedules[target.Path] = s
+
+	go c.runSchedule(ctx, target, s.rescan)
+}
+
+// intervalFor returns the scan interval of the target, falling back to the collector default
+func (c *DirectoryCollector) intervalFor(target config.Directory) time.Duration {
+	if target.ScanInterval > 0 {
+		return time.Duration(target.ScanInterval)
+	}
+
+	return c.scanInterval
+}
+
+// runSchedule scans the directory periodically until the context is cancelled, and whenever a rescan is requested.
+// As each directory is only scanned by a single goroutine, scans of the same directory never overlap.
+func (c *DirectoryCollector) runSchedule(ctx context.Context, target config.Directory, rescan <-chan chan struct{}) {
+	interval := c.intervalFor(target)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// done is closed when the requested rescan finishes, nil for scheduled scans
+	var done chan struct{}
+
+	for {
+		c.scanTarget(ctx, target)
+		c.markScanned(ctx, target)
+
+		if done != nil {
+			close(done)
+			done = nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case done = <-rescan:
+			c.logger.Info("rescan requested", zap.String("directory", target.Path))
+			// The next scheduled scan is a full interval after this one
+			ticker.Reset(interval)
+		}
+	}
+}
+
+// markScanned records that the directories of the target were scanned, unless the schedule was cancelled
+func (c *DirectoryCollector) markScanned(ctx context.Context, target config.Directory) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	if s, ok := c.schedules[target.Path]; ok {
+		s.scanned = true
+	}
+}
+
+// scanTarget scans all the directories of the target. When the target path is a glob pattern, it's expanded
+// on every scan, so new matches are picked up and directories that no longer match are removed.
+func (c *DirectoryCollector) scanTarget(ctx context.Context, target config.Directory) {
+	if !glob.IsPattern(target.Path) {
+		c.scan(ctx, target, glob.Match{Path: target.Path})
+		return
+	}
+
+	matches, err := glob.Expand(target.Path)
+	if err != nil {
+		c.logger.Error("error expanding directory pattern", zap.String("pattern", target.Path), zap.Error(err))
+		return
+	}
+
+	if len(matches) == 0 {
+		c.logger.Warn("directory pattern does not match any directory", zap.String("pattern", target.Path))
+	}
+
+	paths := make([]string, 0, len(matches))
+	for _, match := range matches {
+		paths = append(paths, match.Path)
+	}
+	c.pruneStates(ctx, target, paths)
+
+	for _, match := range matches {
+		if ctx.Err() != nil {
+			return
+		}
+
+		c.scan(ctx, target, match)
+	}
+}
+
+// scan calculates the size of a directory of the target and caches the result.
+// The captures of the match are added as labels to the metrics of the directory.
+func (c *DirectoryCollector) scan(ctx context.Context, target config.Directory, match glob.Match) {
+	directory := match.Path
+	c.logger.Info("collecting directory size", zap.String("directory", directory))
+
+	startedAt := time.Now()
+	result, err := c.getDirectorySize(ctx, target, directory)
+
+	scan := scanResult{
+		labels:    match.Captures,
+		scannedAt: time.Now(),
+		duration:  time.Since(startedAt),
+		err:       err,
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		// A cancelled scan says nothing about the directory, so it's not recorded
+		return
+	case errors.Is(err, fs.ErrNotExist):
+		c.logger.Error("directory does not exist", zap.String("directory", directory))
+	case err != nil:
+		c.logger.Error("error getting directory size", zap.String("directory", directory), zap.Error(err))
+	default:
+		scan.size = result.Size
+		scan.diskUsage = result.DiskUsage
+		scan.excludedSize = result.ExcludedSize
+		scan.counts = entryCounts{
+			files:       result.Files,
+			directories: result.Directories,
+			symlinks:    result.Symlinks,
+		}
+		scan.subdirectories = result.Subdirectories
+		scan.sizeByType = result.SizeByType
+		scan.sizeByAge = result.SizeByAge
+		// Names are resolved before storing the result, as lookups might be slow
+		if result.SizeByUser != nil {
+			scan.sizeByUser = topOwners(result.SizeByUser, target.TopOwners, c.ownerNames.user)
+			scan.sizeByGroup = topOwners(result.SizeByGroup, target.TopOwners, c.ownerNames.group)
+		}
+		scan.oldestFile = result.OldestFile
+		scan.newestFile = result.NewestFile
+		scan.largestFiles = result.LargestFiles
+		scan.largestDirectories = result.LargestDirectories
+		scan.entryErrors = result.Errors
+		scan.filesystem = c.getFilesystem(directory)
+		c.logger.Info("directory size collected", zap.String("directory", directory), zap.Int64("size", result.Size))
+	}
+
+	alerts := c.updateState(ctx, target, directory, scan)
+	c.notify(alerts)
+}
+
+// getFilesystem returns the capacity of the filesystem of the directory, or nil if it's not available
+func (c *DirectoryCollector) getFilesystem(directory string) *filesystem.Info {
+	info, err := filesystem.Stat(directory)
+	if errors.Is(err, filesystem.ErrUnsupported) {
+		return nil
+	}
+	if err != nil {
+		c.logger.Warn("error reading filesystem of directory", zap.String("directory", directory), zap.Error(err))
+		return nil
+	}
+
+	return &info
+}
@@ -0,0 +1,83 @@
+package collector
+
+import (
+	"sort"
+	"time"
+)
+
+// DirectorySummary holds the cached state of a directory, as of its last scan
+type DirectorySummary struct {
+	Name string
+	Path string
+	// Size, DiskUsage, Files and Subdirectories are the values of the last successful scan
+	Size           int64
+	DiskUsage      int64
+	Files          int64
+	Subdirectories int64
+	// LastScan is the time of the last successful scan, zero when the directory was never scanned successfully
+	LastScan     time.Time
+	ScanDuration time.Duration
+	// Error is the reason of the failure of the last scan, empty when it succeeded
+	Error string
+}
+
+// Summaries returns the summaries of all the directories that were scanned, sorted by name and path
+func (c *DirectoryCollector) Summaries() []DirectorySummary {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	summaries := make([]DirectorySummary, 0, len(c.states))
+	for path, state := range c.states {
+		summaries = append(summaries, newDirectorySummary(path, state))
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Name != summaries[j].Name {
+			return summaries[i].Name < summaries[j].Name
+		}
+
+		return summaries[i].Path < summaries[j].Path
+	})
+
+	return summaries
+}
+
+// Summary returns the summary of the directory with the given name.
+// It fails with ErrDirectoryNotFound if no directory with that name was scanned yet, and an
+// AmbiguousNameError if there are several directories with the name.
+func (c *DirectoryCollector) Summary(name string) (DirectorySummary, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	path, err := c.lookup(name)
+	if err != nil {
+		return DirectorySummary{}, err
+	}
+
+	state, ok := c.states[path]
+	if !ok {
+		return DirectorySummary{}, ErrDirectoryNotFound
+	}
+
+	return newDirectorySummary(path, state), nil
+}
+
+// newDirectorySummary creates the summary of the state of a directory
+func newDirectorySummary(path string, state *directoryState) DirectorySummary {
+	summary := DirectorySummary{
+		Name:           state.name,
+		Path:           path,
+		Size:           state.size,
+		DiskUsage:      state.diskUsage,
+		Files:          state.counts.files,
+		Subdirectories: state.counts.directories,
+		LastScan:       state.lastScan,
+		ScanDuration:   state.scanDuration,
+	}
+
+	if state.scanError != nil {
+		summary.Error = state.scanError.Error()
+	}
+
+	return summary
+}
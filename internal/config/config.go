@@ -0,0 +1,609 @@
+// Package config defines the configuration file of the exporter and how to load and validate it.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/brpaz/prom-dirsize-exporter/internal/glob"
+	"github.com/brpaz/prom-dirsize-exporter/internal/walker"
+)
+
+const (
+	// DefaultMetricsPort is the default port where the metrics server listens
+	DefaultMetricsPort = 8080
+	// DefaultMetricsPath is the default path where the metrics are exposed
+	DefaultMetricsPath = "/metrics"
+	// DefaultScanInterval is the default interval between scans of the same directory
+	DefaultScanInterval = 5 * time.Minute
+	// DefaultGrowthWindow is the default time window used to calculate the growth rate of the directories
+	DefaultGrowthWindow = time.Hour
+	// DefaultWebhookRetries is the default number of times a failed webhook request is retried
+	DefaultWebhookRetries = 3
+	// DefaultWebhookRepeatInterval is the default time during which repeated alerts are not sent again
+	DefaultWebhookRepeatInterval = time.Hour
+	// DefaultMetricNamespace is the default namespace of the metric names, like "directory_size_bytes"
+	DefaultMetricNamespace = "directory"
+	// DefaultMaxSubdirectories is the default number of subdirectories reported when depth is set
+	DefaultMaxSubdirectories = 50
+)
+
+// Size modes, that define which size metrics are reported for a directory
+const (
+	// SizeModeApparent reports the apparent size of the files, like "du -b"
+	SizeModeApparent = "apparent"
+	// SizeModeAllocated reports the space allocated on disk for the files, like "du"
+	SizeModeAllocated = "allocated"
+	// SizeModeBoth reports both the apparent size and the allocated space
+	SizeModeBoth = "both"
+)
+
+// labelNameRegex matches valid Prometheus label names
+var labelNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// metricNameRegex matches the parts of the metric names that can be configured. Colons are left out, as they are
+// reserved for recording rules.
+var metricNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// ReservedLabels holds the label names that are set by the exporter on its metrics, and can't be used as extra
+// labels or capture group names, as Prometheus rejects metrics with duplicate label names
+var ReservedLabels = []string{
+	"name", "path", "parent", "reason", "type", "le", "user", "group",
+	"mountpoint", "fstype", "limit", "rank", "entry",
+}
+
+// Config holds the exporter configuration
+type Config struct {
+	MetricsPort     int         `yaml:"metrics_port" toml:"metrics_port"`
+	MetricsPath     string      `yaml:"metrics_path" toml:"metrics_path"`
+	MetricNamespace string      `yaml:"metric_namespace" toml:"metric_namespace"`
+	ScanInterval    Duration    `yaml:"scan_interval" toml:"scan_interval"`
+	OneFileSystem   bool        `yaml:"one_file_system" toml:"one_file_system"`
+	GrowthWindow    Duration    `yaml:"growth_window" toml:"growth_window"`
+	Webhook         Webhook     `yaml:"webhook" toml:"webhook"`
+	Directories     []Directory `yaml:"directories" toml:"directories"`
+}
+
+// Webhook holds the configuration of the webhook that receives the alerts raised by the scans
+type Webhook struct {
+	// URL is where the alerts are sent, as JSON payloads in POST requests. Empty disables the webhook.
+	URL string `yaml:"url" toml:"url"`
+	// GrowthPercent raises an alert when a directory grows more than this percentage between two scans.
+	// 0 disables it. Quota status changes are always sent.
+	GrowthPercent float64 `yaml:"growth_percent" toml:"growth_percent"`
+	// Retries is the number of times a failed request is retried, with an exponential backoff
+	Retries int `yaml:"retries" toml:"retries"`
+	// RepeatInterval is the time during which repeated alerts, for the same directory and status, are not sent again
+	RepeatInterval Duration `yaml:"repeat_interval" toml:"repeat_interval"`
+}
+
+// Directory holds the configuration of a single directory to monitor
+type Directory struct {
+	// Path is the path of the directory to monitor. It can also be a glob pattern, including "**",
+	// that is expanded on every scan, with each match monitored as its own directory. Named capture groups
+	// in the pattern, like "(?P<tenant>[^/]+)", add the part of the path they match as labels.
+	Path string `yaml:"path" toml:"path"`
+	// Name is the value of the "name" label. Defaults to the base name of the path, or as many trailing
+	// segments of the path as needed to tell it apart from the other directories.
+	Name string `yaml:"name" toml:"name"`
+	// ScanInterval overrides the global scan interval for this directory
+	ScanInterval Duration `yaml:"scan_interval" toml:"scan_interval"`
+	// Depth enables the size breakdown of the subdirectories up to the given depth. 0 disables it.
+	Depth int `yaml:"depth" toml:"depth"`
+	// MaxSubdirectories limits the breakdown to the largest subdirectories. Defaults to DefaultMaxSubdirectories.
+	MaxSubdirectories int `yaml:"max_subdirectories" toml:"max_subdirectories"`
+	// TopEntries enables the report of the given number of largest files and subdirectories. 0 disables it.
+	TopEntries int `yaml:"top_entries" toml:"top_entries"`
+	// TopOwners enables the size breakdown by the users and groups that own the entries, limited to the given
+	// number of largest owners, with the rest reported together. 0 disables it.
+	TopOwners int `yaml:"top_owners" toml:"top_owners"`
+	// SizeMode defines which sizes are reported, either SizeModeApparent, SizeModeAllocated or SizeModeBoth.
+	// Defaults to SizeModeApparent.
+	SizeMode string `yaml:"size_mode" toml:"size_mode"`
+	// FileTypes enables the size breakdown by file type, mapping each type to its file extensions, like
+	// {"video": [".mp4", ".mkv"]}. Files with other extensions are reported as walker.OtherFileType.
+	FileTypes map[string][]string `yaml:"file_types" toml:"file_types"`
+	// AgeBuckets enables the size breakdown of the files by the age of their modification time, with the
+	// given buckets in ascending order, like ["7d", "30d", "90d"]. Empty disables it.
+	AgeBuckets []Duration `yaml:"age_buckets" toml:"age_buckets"`
+	// Include holds gitignore style patterns of the entries to scan. When empty, all the entries are scanned.
+	Include []string `yaml:"include" toml:"include"`
+	// Exclude holds gitignore style patterns of entries to leave out of the scan
+	Exclude []string `yaml:"exclude" toml:"exclude"`
+	// Quota holds the expected limits of the directory
+	Quota Quota `yaml:"quota" toml:"quota"`
+	// MetricPrefix is added to the names of the metrics of this directory, after the namespace, like
+	// "directory_<prefix>_size_bytes". Empty keeps the default names.
+	MetricPrefix string `yaml:"metric_prefix" toml:"metric_prefix"`
+	// Labels holds extra labels added to all the metrics of this directory
+	Labels map[string]string `yaml:"labels" toml:"labels"`
+}
+
+// Quota holds the soft and hard limits of a directory. Zero values disable the limit.
+type Quota struct {
+	// SoftBytes is the size, in bytes, above which the directory is reported as over the soft limit
+	SoftBytes int64 `yaml:"soft_bytes" toml:"soft_bytes"`
+	// HardBytes is the size, in bytes, above which the directory is reported as over the hard limit
+	HardBytes int64 `yaml:"hard_bytes" toml:"hard_bytes"`
+	// SoftFiles is the number of files above which the directory is reported as over the soft limit
+	SoftFiles int64 `yaml:"soft_files" toml:"soft_files"`
+	// HardFiles is the number of files above which the directory is reported as over the hard limit
+	HardFiles int64 `yaml:"hard_files" toml:"hard_files"`
+}
+
+// Enabled returns true if any of the limits is set
+func (q Quota) Enabled() bool {
+	return q.SoftBytes > 0 || q.HardBytes > 0 || q.SoftFiles > 0 || q.HardFiles > 0
+}
+
+// validate checks if the quota limits are valid
+func (q Quota) validate() []error {
+	var errs []error
+
+	limits := []struct {
+		name  string
+		value int64
+	}{
+		{"soft_bytes", q.SoftBytes},
+		{"hard_bytes", q.HardBytes},
+		{"soft_files", q.SoftFiles},
+		{"hard_files", q.HardFiles},
+	}
+	for _, limit := range limits {
+		if limit.value < 0 {
+			errs = append(errs, fmt.Errorf("quota %s must not be negative, got %d", limit.name, limit.value))
+		}
+	}
+
+	if q.SoftBytes > 0 && q.HardBytes > 0 && q.SoftBytes > q.HardBytes {
+		errs = append(errs, fmt.Errorf("quota soft_bytes must not be greater than hard_bytes, got %d and %d", q.SoftBytes, q.HardBytes))
+	}
+
+	if q.SoftFiles > 0 && q.HardFiles > 0 && q.SoftFiles > q.HardFiles {
+		errs = append(errs, fmt.Errorf("quota soft_files must not be greater than hard_files, got %d and %d", q.SoftFiles, q.HardFiles))
+	}
+
+	return errs
+}
+
+// Default returns a configuration with the default values
+func Default() *Config {
+	return &Config{
+		MetricsPort:     DefaultMetricsPort,
+		MetricsPath:     DefaultMetricsPath,
+		MetricNamespace: DefaultMetricNamespace,
+		ScanInterval:    Duration(DefaultScanInterval),
+		GrowthWindow:    Duration(DefaultGrowthWindow),
+		Webhook: Webhook{
+			Retries:        DefaultWebhookRetries,
+			RepeatInterval: Duration(DefaultWebhookRepeatInterval),
+		},
+	}
+}
+
+// Load reads the configuration file at the given path. The format is detected from the file extension,
+// and can be either YAML (.yaml, .yml) or TOML (.toml). Values not present in the file keep their defaults.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+
+	cfg := Default()
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("error parsing config file %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing config file %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("error parsing config file %s: unknown field %q", path, undecoded[0].String())
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config file format %q, must be one of .yaml, .yml or .toml", ext)
+	}
+
+	return cfg, nil
+}
+
+// Validate checks if the configuration is valid, returning all the problems found
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.MetricsPort < 1 || c.MetricsPort > 65535 {
+		errs = append(errs, fmt.Errorf("metrics_port must be between 1 and 65535, got %d", c.MetricsPort))
+	}
+
+	if !strings.HasPrefix(c.MetricsPath, "/") {
+		errs = append(errs, fmt.Errorf("metrics_path must start with \"/\", got %q", c.MetricsPath))
+	}
+
+	if !metricNameRegex.MatchString(c.MetricNamespace) {
+		errs = append(errs, fmt.Errorf("metric_namespace must be a valid metric name, got %q", c.MetricNamespace))
+	}
+
+	if c.ScanInterval <= 0 {
+		errs = append(errs, fmt.Errorf("scan_interval must be greater than zero, got %s", c.ScanInterval))
+	}
+
+	if c.GrowthWindow <= 0 {
+		errs = append(errs, fmt.Errorf("growth_window must be greater than zero, got %s", c.GrowthWindow))
+	}
+
+	for _, err := range c.Webhook.validate() {
+		errs = append(errs, fmt.Errorf("webhook: %w", err))
+	}
+
+	names := make(map[string]int, len(c.Directories))
+	for i, dir := range c.Directories {
+		for _, err := range dir.validate() {
+			errs = append(errs, fmt.Errorf("directories[%d]: %w", i, err))
+		}
+
+		// The names of glob pattern matches are only known at scan time, and default names are made unique
+		// by SetDefaultNames
+		if dir.Name == "" || glob.IsPattern(dir.Path) {
+			continue
+		}
+
+		name := dir.Name
+		if previous, ok := names[name]; ok {
+			errs = append(errs, fmt.Errorf("directories[%d]: name %q is already used by directories[%d]", i, name, previous))
+			continue
+		}
+		names[name] = i
+	}
+
+	return errors.Join(errs...)
+}
+
+// SetDefaultNames sets the name of the directories without one to their base name. When it's already used by
+// another directory, as many trailing segments of the path as needed to tell them apart are used instead,
+// like "a/log" and "b/log" for "/a/log" and "/b/log". Glob patterns are left out, as the names of their matches
+// are only known at scan time.
+func (c *Config) SetDefaultNames() {
+	taken := make(map[string]struct{}, len(c.Directories))
+	unnamed := make([]int, 0, len(c.Directories))
+	for i, dir := range c.Directories {
+		switch {
+		case glob.IsPattern(dir.Path):
+		case dir.Name != "":
+			taken[dir.Name] = struct{}{}
+		default:
+			unnamed = append(unnamed, i)
+		}
+	}
+
+	segments := make(map[int][]string, len(unnamed))
+	for _, i := range unnamed {
+		segments[i] = pathSegments(c.Directories[i].Path)
+	}
+
+	for _, i := range unnamed {
+		name := ""
+		for count := 1; count <= len(segments[i]); count++ {
+			name = lastSegments(segments[i], count)
+			if _, ok := taken[name]; ok {
+				continue
+			}
+
+			unique := true
+			for _, j := range unnamed {
+				if j != i && lastSegments(segments[j], count) == name {
+					unique = false
+					break
+				}
+			}
+
+			if unique {
+				break
+			}
+		}
+
+		if name == "" {
+			name = c.Directories[i].LabelName()
+		}
+
+		c.Directories[i].Name = name
+		taken[name] = struct{}{}
+	}
+}
+
+// pathSegments returns the segments of the cleaned path, without the volume name
+func pathSegments(path string) []string {
+	path = filepath.ToSlash(filepath.Clean(path))
+	path = strings.Trim(path[len(filepath.VolumeName(path)):], "/")
+	if path == "" || path == "." {
+		return nil
+	}
+
+	return strings.Split(path, "/")
+}
+
+// lastSegments joins the last count segments with slashes
+func lastSegments(segments []string, count int) string {
+	if count > len(segments) {
+		count = len(segments)
+	}
+
+	return strings.Join(segments[len(segments)-count:], "/")
+}
+
+// validate checks if the webhook configuration is valid
+func (w Webhook) validate() []error {
+	var errs []error
+
+	if w.URL != "" {
+		if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("url must be an absolute http or https URL, got %q", w.URL))
+		}
+	} else if w.GrowthPercent > 0 {
+		errs = append(errs, errors.New("url is required when growth_percent is set"))
+	}
+
+	if w.GrowthPercent < 0 {
+		errs = append(errs, fmt.Errorf("growth_percent must not be negative, got %g", w.GrowthPercent))
+	}
+
+	if w.Retries < 0 {
+		errs = append(errs, fmt.Errorf("retries must not be negative, got %d", w.Retries))
+	}
+
+	if w.RepeatInterval < 0 {
+		errs = append(errs, fmt.Errorf("repeat_interval must not be negative, got %s", w.RepeatInterval))
+	}
+
+	return errs
+}
+
+// LabelName returns the value of the "name" label for the directory
+func (d Directory) LabelName() string {
+	if d.Name != "" {
+		return d.Name
+	}
+
+	return filepath.Base(d.Path)
+}
+
+// SubdirectoriesLimit returns the maximum number of subdirectories to report in the breakdown
+func (d Directory) SubdirectoriesLimit() int {
+	if d.MaxSubdirectories > 0 {
+		return d.MaxSubdirectories
+	}
+
+	return DefaultMaxSubdirectories
+}
+
+// ReportsApparentSize returns true if the apparent size of the directory should be reported
+func (d Directory) ReportsApparentSize() bool {
+	return d.SizeMode != SizeModeAllocated
+}
+
+// ReportsDiskUsage returns true if the space allocated on disk for the directory should be reported
+func (d Directory) ReportsDiskUsage() bool {
+	return d.SizeMode == SizeModeAllocated || d.SizeMode == SizeModeBoth
+}
+
+// FileTypeExtensions returns the file types indexed by their extensions, in lower case and with a leading dot.
+// It returns nil when the breakdown by file type is disabled.
+func (d Directory) FileTypeExtensions() map[string]string {
+	if len(d.FileTypes) == 0 {
+		return nil
+	}
+
+	types := make(map[string]string)
+	for fileType, extensions := range d.FileTypes {
+		for _, ext := range extensions {
+			types[normalizeExtension(ext)] = fileType
+		}
+	}
+
+	return types
+}
+
+// AgeBucketDurations returns the age buckets as durations, or nil when the breakdown by age is disabled
+func (d Directory) AgeBucketDurations() []time.Duration {
+	if len(d.AgeBuckets) == 0 {
+		return nil
+	}
+
+	buckets := make([]time.Duration, 0, len(d.AgeBuckets))
+	for _, bucket := range d.AgeBuckets {
+		buckets = append(buckets, time.Duration(bucket))
+	}
+
+	return buckets
+}
+
+// normalizeExtension returns the extension in lower case and with a leading dot
+func normalizeExtension(ext string) string {
+	return "." + strings.TrimPrefix(strings.ToLower(ext), ".")
+}
+
+// validate checks if the directory configuration is valid
+func (d Directory) validate() []error {
+	var errs []error
+
+	if d.Path == "" {
+		errs = append(errs, errors.New("path is required"))
+	}
+
+	if glob.IsPattern(d.Path) {
+		if err := glob.Validate(d.Path); err != nil {
+			errs = append(errs, fmt.Errorf("invalid path pattern %q: %w", d.Path, err))
+		}
+
+		if d.Name != "" {
+			errs = append(errs, errors.New("name can't be set when path is a glob pattern, each match is named after its path relative to the pattern"))
+		}
+
+		errs = append(errs, d.validateCaptures()...)
+	}
+
+	if d.ScanInterval < 0 {
+		errs = append(errs, fmt.Errorf("scan_interval must not be negative, got %s", d.ScanInterval))
+	}
+
+	if d.Depth < 0 {
+		errs = append(errs, fmt.Errorf("depth must not be negative, got %d", d.Depth))
+	}
+
+	if d.MaxSubdirectories < 0 {
+		errs = append(errs, fmt.Errorf("max_subdirectories must not be negative, got %d", d.MaxSubdirectories))
+	}
+
+	if d.TopEntries < 0 {
+		errs = append(errs, fmt.Errorf("top_entries must not be negative, got %d", d.TopEntries))
+	}
+
+	if d.TopOwners < 0 {
+		errs = append(errs, fmt.Errorf("top_owners must not be negative, got %d", d.TopOwners))
+	}
+
+	switch d.SizeMode {
+	case "", SizeModeApparent, SizeModeAllocated, SizeModeBoth:
+	default:
+		errs = append(errs, fmt.Errorf("size_mode must be one of %q, %q or %q, got %q",
+			SizeModeApparent, SizeModeAllocated, SizeModeBoth, d.SizeMode))
+	}
+
+	// The subdirectories breakdown is only reported in the apparent size metric
+	if d.SizeMode == SizeModeAllocated && d.Depth > 0 {
+		errs = append(errs, fmt.Errorf("depth requires size_mode %q or %q, got %q", SizeModeApparent, SizeModeBoth, d.SizeMode))
+	}
+
+	if d.MetricPrefix != "" && !metricNameRegex.MatchString(d.MetricPrefix) {
+		errs = append(errs, fmt.Errorf("metric_prefix must be a valid metric name, got %q", d.MetricPrefix))
+	}
+
+	errs = append(errs, d.validateFileTypes()...)
+	errs = append(errs, d.Quota.validate()...)
+
+	for i, bucket := range d.AgeBuckets {
+		if bucket <= 0 {
+			errs = append(errs, fmt.Errorf("age_buckets must be greater than zero, got %s", bucket))
+			continue
+		}
+
+		if i > 0 && bucket <= d.AgeBuckets[i-1] {
+			errs = append(errs, fmt.Errorf("age_buckets must be in ascending order, got %s after %s", bucket, d.AgeBuckets[i-1]))
+		}
+	}
+
+	for _, pattern := range d.Include {
+		if err := walker.ValidatePattern(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("invalid include pattern %q: %w", pattern, err))
+		}
+	}
+
+	for _, pattern := range d.Exclude {
+		if err := walker.ValidatePattern(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err))
+		}
+	}
+
+	for name := range d.Labels {
+		if !labelNameRegex.MatchString(name) || strings.HasPrefix(name, "__") {
+			errs = append(errs, fmt.Errorf("invalid label name %q", name))
+			continue
+		}
+
+		for _, reserved := range ReservedLabels {
+			if name == reserved {
+				errs = append(errs, fmt.Errorf("label %q is reserved and can't be overridden", name))
+			}
+		}
+	}
+
+	return errs
+}
+
+// validateCaptures checks if the names of the capture groups of the path pattern are valid label names,
+// that don't conflict with the exporter labels or the static labels of the directory
+func (d Directory) validateCaptures() []error {
+	names, err := glob.CaptureNames(d.Path)
+	if err != nil {
+		// Invalid patterns are already reported by glob.Validate
+		return nil
+	}
+
+	var errs []error
+	seen := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if !labelNameRegex.MatchString(name) || strings.HasPrefix(name, "__") {
+			errs = append(errs, fmt.Errorf("capture group %q is not a valid label name", name))
+			continue
+		}
+
+		if _, ok := seen[name]; ok {
+			errs = append(errs, fmt.Errorf("capture group %q is used more than once in the path pattern", name))
+			continue
+		}
+		seen[name] = struct{}{}
+
+		for _, reserved := range ReservedLabels {
+			if name == reserved {
+				errs = append(errs, fmt.Errorf("capture group %q is a reserved label name", name))
+			}
+		}
+
+		if _, ok := d.Labels[name]; ok {
+			errs = append(errs, fmt.Errorf("capture group %q conflicts with the label of the same name", name))
+		}
+	}
+
+	return errs
+}
+
+// validateFileTypes checks if the file types are valid, with each extension mapped to a single type
+func (d Directory) validateFileTypes() []error {
+	var errs []error
+
+	fileTypes := make([]string, 0, len(d.FileTypes))
+	for fileType := range d.FileTypes {
+		fileTypes = append(fileTypes, fileType)
+	}
+	sort.Strings(fileTypes)
+
+	seen := make(map[string]string)
+	for _, fileType := range fileTypes {
+		if fileType == "" {
+			errs = append(errs, errors.New("file type name must not be empty"))
+		}
+
+		for _, ext := range d.FileTypes[fileType] {
+			normalized := normalizeExtension(ext)
+			if normalized == "." {
+				errs = append(errs, fmt.Errorf("file type %q has an empty extension", fileType))
+				continue
+			}
+
+			if previous, ok := seen[normalized]; ok && previous != fileType {
+				errs = append(errs, fmt.Errorf("extension %q is mapped to both file types %q and %q", normalized, previous, fileType))
+				continue
+			}
+			seen[normalized] = fileType
+		}
+	}
+
+	return errs
+}
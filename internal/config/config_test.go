@@ -0,0 +1,448 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/prom-dirsize-exporter/internal/config"
+)
+
+// writeConfig writes a config file with the given name and content to a temporary directory
+func writeConfig(t *testing.T, name string, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	expected := &config.Config{
+		MetricsPort:     9100,
+		MetricsPath:     "/custom-metrics",
+		MetricNamespace: "dirsize",
+		ScanInterval:    config.Duration(10 * time.Minute),
+		OneFileSystem:   true,
+		GrowthWindow:    config.Duration(30 * time.Minute),
+		Webhook: config.Webhook{
+			URL:            "http://alerts.example.com/hook",
+			GrowthPercent:  25,
+			Retries:        5,
+			RepeatInterval: config.Duration(config.DefaultWebhookRepeatInterval),
+		},
+		Directories: []config.Directory{
+			{
+				Path:              "/var/log",
+				Name:              "logs",
+				ScanInterval:      config.Duration(time.Minute),
+				Depth:             2,
+				MaxSubdirectories: 10,
+				AgeBuckets:        []config.Duration{config.Duration(7 * 24 * time.Hour), config.Duration(30 * 24 * time.Hour)},
+				Include:           []string{"nginx/"},
+				Exclude:           []string{"*.tmp"},
+				Quota:             config.Quota{SoftBytes: 1 << 30, HardBytes: 2 << 30},
+				MetricPrefix:      "logs",
+				Labels:            map[string]string{"team": "platform"},
+			},
+			{
+				Path: "/var/tmp",
+			},
+		},
+	}
+
+	for _, file := range []string{"config.yaml", "config.toml"} {
+		t.Run(file, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := config.Load(filepath.Join("testdata", file))
+			require.NoError(t, err)
+
+			assert.Equal(t, expected, cfg)
+			assert.NoError(t, cfg.Validate())
+		})
+	}
+}
+
+func TestLoad_KeepsDefaultsForMissingValues(t *testing.T) {
+	t.Parallel()
+
+	path := writeConfig(t, "config.yml", "directories:\n  - path: /data\n")
+
+	cfg, err := config.Load(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, config.Default().MetricsPort, cfg.MetricsPort)
+	assert.Equal(t, config.Default().MetricsPath, cfg.MetricsPath)
+	assert.Equal(t, config.Default().ScanInterval, cfg.ScanInterval)
+}
+
+func TestLoad_ReturnsError(t *testing.T) {
+	t.Parallel()
+
+	scenarios := []struct {
+		name          string
+		file          string
+		content       string
+		expectedError string
+	}{
+		{
+			name:          "Unsupported format",
+			file:          "config.json",
+			content:       "{}",
+			expectedError: "unsupported config file format",
+		},
+		{
+			name:          "Unknown YAML field",
+			file:          "config.yaml",
+			content:       "unknown: true\n",
+			expectedError: "field unknown not found",
+		},
+		{
+			name:          "Unknown TOML field",
+			file:          "config.toml",
+			content:       "unknown = true\n",
+			expectedError: "unknown field \"unknown\"",
+		},
+		{
+			name:          "Invalid duration",
+			file:          "config.yaml",
+			content:       "scan_interval: often\n",
+			expectedError: "invalid duration",
+		},
+		{
+			name:          "Invalid duration in days",
+			file:          "config.yaml",
+			content:       "scan_interval: 1d5\n",
+			expectedError: "invalid duration \"1d5\"",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := config.Load(writeConfig(t, scenario.file, scenario.content))
+
+			assert.ErrorContains(t, err, scenario.expectedError)
+		})
+	}
+}
+
+func TestLoad_WithMissingFile_ReturnsError(t *testing.T) {
+	t.Parallel()
+
+	_, err := config.Load(filepath.Join(t.TempDir(), "missing.yaml"))
+
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestValidate_ReturnsError(t *testing.T) {
+	t.Parallel()
+
+	scenarios := []struct {
+		name          string
+		directories   []config.Directory
+		expectedError string
+	}{
+		{
+			name:          "Missing path",
+			directories:   []config.Directory{{Name: "data"}},
+			expectedError: "directories[0]: path is required",
+		},
+		{
+			name:          "Duplicated name",
+			directories:   []config.Directory{{Path: "/a/data", Name: "data"}, {Path: "/b/data", Name: "data"}},
+			expectedError: "directories[1]: name \"data\" is already used by directories[0]",
+		},
+		{
+			name:          "Invalid include pattern",
+			directories:   []config.Directory{{Path: "/data", Include: []string{"/"}}},
+			expectedError: "directories[0]: invalid include pattern \"/\"",
+		},
+		{
+			name:          "Invalid exclude pattern",
+			directories:   []config.Directory{{Path: "/data", Exclude: []string{"[a-"}}},
+			expectedError: "directories[0]: invalid exclude pattern \"[a-\"",
+		},
+		{
+			name:          "Invalid label name",
+			directories:   []config.Directory{{Path: "/data", Labels: map[string]string{"my-team": "a"}}},
+			expectedError: "directories[0]: invalid label name \"my-team\"",
+		},
+		{
+			name:          "Reserved label name",
+			directories:   []config.Directory{{Path: "/data", Labels: map[string]string{"path": "a"}}},
+			expectedError: "directories[0]: label \"path\" is reserved",
+		},
+		{
+			name:          "Reserved label name of a breakdown metric",
+			directories:   []config.Directory{{Path: "/data", Labels: map[string]string{"reason": "a"}}},
+			expectedError: "directories[0]: label \"reason\" is reserved",
+		},
+		{
+			name:          "Invalid metric prefix",
+			directories:   []config.Directory{{Path: "/data", MetricPrefix: "my-logs"}},
+			expectedError: "directories[0]: metric_prefix must be a valid metric name, got \"my-logs\"",
+		},
+		{
+			name:          "Invalid path pattern",
+			directories:   []config.Directory{{Path: "/srv/[a-/data"}},
+			expectedError: "directories[0]: invalid path pattern \"/srv/[a-/data\"",
+		},
+		{
+			name:          "Name with path pattern",
+			directories:   []config.Directory{{Path: "/srv/*/data", Name: "data"}},
+			expectedError: "directories[0]: name can't be set when path is a glob pattern",
+		},
+		{
+			name:          "Invalid capture group name",
+			directories:   []config.Directory{{Path: "/srv/(?P<1tenant>[^/]+)/data"}},
+			expectedError: "directories[0]: capture group \"1tenant\" is not a valid label name",
+		},
+		{
+			name:          "Reserved capture group name",
+			directories:   []config.Directory{{Path: "/srv/(?P<name>[^/]+)/data"}},
+			expectedError: "directories[0]: capture group \"name\" is a reserved label name",
+		},
+		{
+			name:          "Reserved capture group name of a breakdown metric",
+			directories:   []config.Directory{{Path: "/srv/(?P<type>[^/]+)/data"}},
+			expectedError: "directories[0]: capture group \"type\" is a reserved label name",
+		},
+		{
+			name:          "Capture group conflicts with label",
+			directories:   []config.Directory{{Path: "/srv/(?P<team>[^/]+)/data", Labels: map[string]string{"team": "a"}}},
+			expectedError: "directories[0]: capture group \"team\" conflicts with the label of the same name",
+		},
+		{
+			name:          "Duplicated capture group",
+			directories:   []config.Directory{{Path: "/srv/(?P<env>[^/]+)/(?P<env>[^/]+)"}},
+			expectedError: "directories[0]: capture group \"env\" is used more than once",
+		},
+		{
+			name:          "Negative depth",
+			directories:   []config.Directory{{Path: "/data", Depth: -1}},
+			expectedError: "directories[0]: depth must not be negative",
+		},
+		{
+			name:          "Negative max subdirectories",
+			directories:   []config.Directory{{Path: "/data", MaxSubdirectories: -1}},
+			expectedError: "directories[0]: max_subdirectories must not be negative",
+		},
+		{
+			name:          "Negative top entries",
+			directories:   []config.Directory{{Path: "/data", TopEntries: -1}},
+			expectedError: "directories[0]: top_entries must not be negative",
+		},
+		{
+			name:          "Negative top owners",
+			directories:   []config.Directory{{Path: "/data", TopOwners: -1}},
+			expectedError: "directories[0]: top_owners must not be negative",
+		},
+		{
+			name:          "Invalid size mode",
+			directories:   []config.Directory{{Path: "/data", SizeMode: "blocks"}},
+			expectedError: "directories[0]: size_mode must be one of \"apparent\", \"allocated\" or \"both\", got \"blocks\"",
+		},
+		{
+			name:          "Depth with allocated size mode",
+			directories:   []config.Directory{{Path: "/data", Depth: 2, SizeMode: config.SizeModeAllocated}},
+			expectedError: "directories[0]: depth requires size_mode \"apparent\" or \"both\", got \"allocated\"",
+		},
+		{
+			name:          "Extension with multiple file types",
+			directories:   []config.Directory{{Path: "/data", FileTypes: map[string][]string{"archive": {".gz"}, "logs": {"GZ"}}}},
+			expectedError: "directories[0]: extension \".gz\" is mapped to both file types \"archive\" and \"logs\"",
+		},
+		{
+			name:          "Empty file type extension",
+			directories:   []config.Directory{{Path: "/data", FileTypes: map[string][]string{"video": {""}}}},
+			expectedError: "directories[0]: file type \"video\" has an empty extension",
+		},
+		{
+			name:          "Age buckets not in ascending order",
+			directories:   []config.Directory{{Path: "/data", AgeBuckets: []config.Duration{config.Duration(time.Hour), config.Duration(time.Minute)}}},
+			expectedError: "directories[0]: age_buckets must be in ascending order, got 1m0s after 1h0m0s",
+		},
+		{
+			name:          "Negative quota",
+			directories:   []config.Directory{{Path: "/data", Quota: config.Quota{HardFiles: -1}}},
+			expectedError: "directories[0]: quota hard_files must not be negative, got -1",
+		},
+		{
+			name:          "Quota soft limit over hard limit",
+			directories:   []config.Directory{{Path: "/data", Quota: config.Quota{SoftBytes: 200, HardBytes: 100}}},
+			expectedError: "directories[0]: quota soft_bytes must not be greater than hard_bytes, got 200 and 100",
+		},
+		{
+			name:          "Negative scan interval",
+			directories:   []config.Directory{{Path: "/data", ScanInterval: config.Duration(-time.Second)}},
+			expectedError: "directories[0]: scan_interval must not be negative",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := config.Default()
+			cfg.Directories = scenario.directories
+
+			assert.ErrorContains(t, cfg.Validate(), scenario.expectedError)
+		})
+	}
+}
+
+func TestValidate_Webhook_ReturnsError(t *testing.T) {
+	t.Parallel()
+
+	scenarios := []struct {
+		name          string
+		webhook       config.Webhook
+		expectedError string
+	}{
+		{
+			name:          "Relative URL",
+			webhook:       config.Webhook{URL: "/hook"},
+			expectedError: "webhook: url must be an absolute http or https URL, got \"/hook\"",
+		},
+		{
+			name:          "Growth percent without URL",
+			webhook:       config.Webhook{GrowthPercent: 10},
+			expectedError: "webhook: url is required when growth_percent is set",
+		},
+		{
+			name:          "Negative retries",
+			webhook:       config.Webhook{URL: "https://example.com", Retries: -1},
+			expectedError: "webhook: retries must not be negative",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := config.Default()
+			cfg.Webhook = scenario.webhook
+
+			assert.ErrorContains(t, cfg.Validate(), scenario.expectedError)
+		})
+	}
+}
+
+func TestValidate_AllowsPatternsWithSameBaseName(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.Directories = []config.Directory{{Path: "/srv/*/data"}, {Path: "/mnt/*/data"}}
+
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_AllowsDefaultNamesWithSameBaseName(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.Directories = []config.Directory{{Path: "/a/log"}, {Path: "/b/log"}}
+
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestConfig_SetDefaultNames(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.Directories = []config.Directory{
+		{Path: "/a/log"},
+		{Path: "/b/log"},
+		{Path: "/srv/data"},
+		{Path: "/mnt/backups", Name: "cache"},
+		{Path: "/var/cache"},
+		{Path: "/srv/*/uploads"},
+	}
+
+	cfg.SetDefaultNames()
+
+	names := make([]string, 0, len(cfg.Directories))
+	for _, dir := range cfg.Directories {
+		names = append(names, dir.Name)
+	}
+
+	assert.Equal(t, []string{"a/log", "b/log", "data", "cache", "var/cache", ""}, names)
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_ReportsAllErrors(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.MetricsPort = 0
+	cfg.MetricsPath = "metrics"
+	cfg.MetricNamespace = "my-exporter"
+	cfg.ScanInterval = 0
+	cfg.GrowthWindow = 0
+
+	err := cfg.Validate()
+
+	assert.ErrorContains(t, err, "metrics_port must be between 1 and 65535")
+	assert.ErrorContains(t, err, "metrics_path must start with \"/\"")
+	assert.ErrorContains(t, err, "metric_namespace must be a valid metric name, got \"my-exporter\"")
+	assert.ErrorContains(t, err, "scan_interval must be greater than zero")
+	assert.ErrorContains(t, err, "growth_window must be greater than zero")
+}
+
+func TestDirectory_SubdirectoriesLimit(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, config.DefaultMaxSubdirectories, config.Directory{Path: "/data"}.SubdirectoriesLimit())
+	assert.Equal(t, 5, config.Directory{Path: "/data", MaxSubdirectories: 5}.SubdirectoriesLimit())
+}
+
+func TestDirectory_SizeMode(t *testing.T) {
+	t.Parallel()
+
+	scenarios := []struct {
+		sizeMode          string
+		expectedApparent  bool
+		expectedDiskUsage bool
+	}{
+		{sizeMode: "", expectedApparent: true, expectedDiskUsage: false},
+		{sizeMode: config.SizeModeApparent, expectedApparent: true, expectedDiskUsage: false},
+		{sizeMode: config.SizeModeAllocated, expectedApparent: false, expectedDiskUsage: true},
+		{sizeMode: config.SizeModeBoth, expectedApparent: true, expectedDiskUsage: true},
+	}
+
+	for _, scenario := range scenarios {
+		dir := config.Directory{Path: "/data", SizeMode: scenario.sizeMode}
+
+		assert.Equal(t, scenario.expectedApparent, dir.ReportsApparentSize(), "size mode %q", scenario.sizeMode)
+		assert.Equal(t, scenario.expectedDiskUsage, dir.ReportsDiskUsage(), "size mode %q", scenario.sizeMode)
+	}
+}
+
+func TestDirectory_FileTypeExtensions(t *testing.T) {
+	t.Parallel()
+
+	dir := config.Directory{
+		Path:      "/data",
+		FileTypes: map[string][]string{"video": {".mp4", "MKV"}, "archive": {"tar.gz"}},
+	}
+
+	assert.Equal(t, map[string]string{".mp4": "video", ".mkv": "video", ".tar.gz": "archive"}, dir.FileTypeExtensions())
+	assert.Nil(t, config.Directory{Path: "/data"}.FileTypeExtensions())
+}
+
+func TestDirectory_LabelName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "log", config.Directory{Path: "/var/log"}.LabelName())
+	assert.Equal(t, "logs", config.Directory{Path: "/var/log", Name: "logs"}.LabelName())
+}
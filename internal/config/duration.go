@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Duration is a time.Duration that can be parsed from strings like "5m" or "1h30m" in the config file.
+// A number of days can also be used as a prefix, like "7d" or "1d12h".
+type Duration time.Duration
+
+// day is the duration of a day, that time.ParseDuration doesn't support
+const day = 24 * time.Hour
+
+// String returns the duration formatted like time.Duration
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, used by the TOML decoder
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := parseDuration(string(text))
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	var text string
+	if err := value.Decode(&text); err != nil {
+		return err
+	}
+
+	return d.UnmarshalText([]byte(text))
+}
+
+// parseDuration parses a duration like time.ParseDuration, with an optional number of days as a prefix
+func parseDuration(text string) (time.Duration, error) {
+	days, rest, found := strings.Cut(text, "d")
+	if !found {
+		return time.ParseDuration(text)
+	}
+
+	n, err := strconv.Atoi(days)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", text)
+	}
+
+	duration := time.Duration(n) * day
+	if rest == "" {
+		return duration, nil
+	}
+
+	parsed, err := time.ParseDuration(rest)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", text)
+	}
+
+	if n < 0 {
+		return duration - parsed, nil
+	}
+
+	return duration + parsed, nil
+}
@@ -0,0 +1,19 @@
+// Package filesystem provides information about the filesystem where a directory is stored.
+package filesystem
+
+import "errors"
+
+// ErrUnsupported is returned when the filesystem information is not available on this platform
+var ErrUnsupported = errors.New("filesystem information is not supported on this platform")
+
+// Info holds the capacity of a filesystem and where it's mounted
+type Info struct {
+	// Mountpoint is the path where the filesystem is mounted. It's empty when it can't be found.
+	Mountpoint string
+	// Type is the filesystem type, like "ext4" or "nfs". It's empty when it can't be found.
+	Type string
+	// Size is the total size of the filesystem in bytes
+	Size uint64
+	// Free is the space, in bytes, available to unprivileged users
+	Free uint64
+}
@@ -0,0 +1,35 @@
+package filesystem_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/prom-dirsize-exporter/internal/filesystem"
+)
+
+func TestStat(t *testing.T) {
+	t.Parallel()
+
+	info, err := filesystem.Stat(t.TempDir())
+	if errors.Is(err, filesystem.ErrUnsupported) {
+		t.Skip("filesystem information is not supported on this platform")
+	}
+	require.NoError(t, err)
+
+	assert.Greater(t, info.Size, uint64(0))
+	assert.LessOrEqual(t, info.Free, info.Size)
+	assert.NotEmpty(t, info.Mountpoint)
+	assert.NotEmpty(t, info.Type)
+}
+
+func TestStat_WithNonExistingPath_ReturnsError(t *testing.T) {
+	t.Parallel()
+
+	_, err := filesystem.Stat(filepath.Join(t.TempDir(), "missing"))
+
+	assert.Error(t, err)
+}
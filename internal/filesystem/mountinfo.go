@@ -0,0 +1,69 @@
+package filesystem
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// findMount returns the mount point and filesystem type of the path, read from a mountinfo file in the format
+// described in proc(5). The path must be absolute and have its symbolic links resolved. When mounts are stacked
+// on the same mount point, the last one wins, as it's the visible one.
+func findMount(r io.Reader, path string) (mountpoint string, fsType string) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		point, pointType, ok := parseMountInfoLine(scanner.Text())
+		if !ok || !isUnder(path, point) || len(point) < len(mountpoint) {
+			continue
+		}
+
+		mountpoint, fsType = point, pointType
+	}
+
+	return mountpoint, fsType
+}
+
+// parseMountInfoLine returns the mount point and filesystem type of a mountinfo line, like
+// "36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue"
+func parseMountInfoLine(line string) (mountpoint string, fsType string, ok bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 5 {
+		return "", "", false
+	}
+
+	// The optional fields end with a "-" separator, followed by the filesystem type
+	for i := 5; i < len(fields)-1; i++ {
+		if fields[i] == "-" {
+			return unescapeMountPath(fields[4]), fields[i+1], true
+		}
+	}
+
+	return "", "", false
+}
+
+// unescapeMountPath decodes the octal escapes, like "\040" for spaces, used in mountinfo paths
+func unescapeMountPath(path string) string {
+	if !strings.Contains(path, `\`) {
+		return path
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+4 <= len(path) {
+			if c, err := strconv.ParseUint(path[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(path[i])
+	}
+
+	return b.String()
+}
+
+// isUnder returns true if the path is the directory or is inside it
+func isUnder(path string, dir string) bool {
+	return path == dir || dir == "/" || strings.HasPrefix(path, dir+"/")
+}
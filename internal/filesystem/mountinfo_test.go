@@ -0,0 +1,49 @@
+package filesystem
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const mountInfo = `22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw
+23 22 0:21 / /proc rw,nosuid shared:12 - proc proc rw
+24 22 8:2 / /srv rw,relatime shared:2 - xfs /dev/sda2 rw
+25 24 0:45 / /srv/backups rw,relatime shared:3 master:1 - nfs4 server:/backups rw
+26 22 8:3 / /mnt/my\040disk rw,relatime - ext4 /dev/sda3 rw
+27 24 0:46 / /srv/backups rw,relatime - tmpfs tmpfs rw
+`
+
+func TestFindMount(t *testing.T) {
+	t.Parallel()
+
+	scenarios := []struct {
+		path               string
+		expectedMountpoint string
+		expectedType       string
+	}{
+		{path: "/var/log", expectedMountpoint: "/", expectedType: "ext4"},
+		{path: "/srv", expectedMountpoint: "/srv", expectedType: "xfs"},
+		{path: "/srv/data", expectedMountpoint: "/srv", expectedType: "xfs"},
+		{path: "/srv/backupsold", expectedMountpoint: "/srv", expectedType: "xfs"},
+		{path: "/srv/backups/daily", expectedMountpoint: "/srv/backups", expectedType: "tmpfs"},
+		{path: "/mnt/my disk/photos", expectedMountpoint: "/mnt/my disk", expectedType: "ext4"},
+	}
+
+	for _, scenario := range scenarios {
+		mountpoint, fsType := findMount(strings.NewReader(mountInfo), scenario.path)
+
+		assert.Equal(t, scenario.expectedMountpoint, mountpoint, "path %q", scenario.path)
+		assert.Equal(t, scenario.expectedType, fsType, "path %q", scenario.path)
+	}
+}
+
+func TestFindMount_WithInvalidLines_IgnoresThem(t *testing.T) {
+	t.Parallel()
+
+	mountpoint, fsType := findMount(strings.NewReader("invalid\n22 1 8:1 / / rw shared:1 ext4\n"), "/data")
+
+	assert.Empty(t, mountpoint)
+	assert.Empty(t, fsType)
+}
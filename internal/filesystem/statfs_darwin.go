@@ -0,0 +1,38 @@
+//go:build darwin
+
+package filesystem
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// Stat returns the capacity and mount point of the filesystem where the path is stored
+func Stat(path string) (Info, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return Info{}, fmt.Errorf("error reading filesystem of %s: %w", path, err)
+	}
+
+	blockSize := uint64(stat.Bsize)
+
+	return Info{
+		Size:       stat.Blocks * blockSize,
+		Free:       stat.Bavail * blockSize,
+		Mountpoint: cString(stat.Mntonname[:]),
+		Type:       cString(stat.Fstypename[:]),
+	}, nil
+}
+
+// cString converts a null terminated C string to a Go string
+func cString(chars []int8) string {
+	b := make([]byte, 0, len(chars))
+	for _, c := range chars {
+		if c == 0 {
+			break
+		}
+		b = append(b, byte(c))
+	}
+
+	return string(b)
+}
@@ -0,0 +1,43 @@
+//go:build linux
+
+package filesystem
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// mountInfoPath is the file that lists the mount points of the process
+const mountInfoPath = "/proc/self/mountinfo"
+
+// Stat returns the capacity and mount point of the filesystem where the path is stored
+func Stat(path string) (Info, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return Info{}, fmt.Errorf("error resolving %s: %w", path, err)
+	}
+
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(resolved, &stat); err != nil {
+		return Info{}, fmt.Errorf("error reading filesystem of %s: %w", path, err)
+	}
+
+	blockSize := uint64(stat.Bsize)
+	info := Info{
+		Size: stat.Blocks * blockSize,
+		Free: stat.Bavail * blockSize,
+	}
+
+	// The mount point is only extra context, so the capacity is still reported without it
+	f, err := os.Open(mountInfoPath)
+	if err != nil {
+		return info, nil
+	}
+	defer f.Close()
+
+	info.Mountpoint, info.Type = findMount(f, resolved)
+
+	return info, nil
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package filesystem
+
+// Stat returns the capacity of the filesystem where the path is stored.
+// It's not available on this platform, so ErrUnsupported is always returned.
+func Stat(_ string) (Info, error) {
+	return Info{}, ErrUnsupported
+}
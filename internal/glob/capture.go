@@ -0,0 +1,114 @@
+package glob
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// captureStarts holds the prefixes of the regular expression named capture groups, that can be used in
+// pattern segments to capture part of the matched path, like "/srv/(?P<tenant>[^/]+)/data"
+var captureStarts = []string{"(?P<", "(?<"}
+
+// hasCapture returns true if the pattern segment contains a named capture group
+func hasCapture(segment string) bool {
+	for _, start := range captureStarts {
+		if strings.Contains(segment, start) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compileSegment compiles a pattern segment with capture groups into a regular expression matching the whole
+// segment. Glob meta characters outside of the groups keep their meaning, and the groups are used as they are.
+func compileSegment(segment string) (*regexp.Regexp, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+
+	for i := 0; i < len(segment); i++ {
+		switch c := segment[i]; c {
+		case '*':
+			expr.WriteString("[^/]*")
+		case '?':
+			expr.WriteString("[^/]")
+		case '\\':
+			if i+1 < len(segment) {
+				i++
+				expr.WriteString(regexp.QuoteMeta(segment[i : i+1]))
+			}
+		case '[':
+			end := strings.IndexByte(segment[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated character class in %q", segment)
+			}
+			expr.WriteString(segment[i : i+end+2])
+			i += end + 1
+		case '(':
+			end := groupEnd(segment, i)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated group in %q", segment)
+			}
+			expr.WriteString(segment[i : end+1])
+			i = end
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	expr.WriteString("$")
+
+	return regexp.Compile(expr.String())
+}
+
+// groupEnd returns the index of the parenthesis that closes the group starting at start, or -1 if it's not closed.
+// Escaped characters and character classes are skipped, as they might contain parentheses.
+func groupEnd(s string, start int) int {
+	depth := 0
+	inClass := false
+
+	for i := start; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\':
+			i++
+		case inClass:
+			inClass = c != ']'
+		case c == '[':
+			inClass = true
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// CaptureNames returns the names of the capture groups of the pattern, in the order they appear
+func CaptureNames(pattern string) ([]string, error) {
+	var names []string
+
+	for _, segment := range splitPattern(pattern) {
+		if !hasCapture(segment) {
+			continue
+		}
+
+		re, err := compileSegment(segment)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range re.SubexpNames() {
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+
+	return names, nil
+}
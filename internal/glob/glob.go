@@ -0,0 +1,237 @@
+// Package glob expands glob patterns into the list of directories they match.
+// Besides the filepath.Match syntax, the "**" segment matches any number of nested directories, and segments
+// can contain regular expression named capture groups, like "(?P<tenant>[^/]+)", to extract parts of the
+// matched paths.
+package glob
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// doubleStar is the pattern segment that matches zero or more directories
+const doubleStar = "**"
+
+// Match is a directory matched by a pattern
+type Match struct {
+	Path string
+	// Captures holds the values of the named capture groups of the pattern, indexed by their name
+	Captures map[string]string
+}
+
+// IsPattern returns true if the path contains any glob meta characters or capture groups
+func IsPattern(path string) bool {
+	return strings.ContainsAny(path, `*?[`) || hasCapture(path)
+}
+
+// StaticPrefix returns the leading part of the pattern that has no meta characters.
+// For example, the static prefix of "/srv/tenants/*/uploads" is "/srv/tenants".
+func StaticPrefix(pattern string) string {
+	prefix := filepath.VolumeName(pattern)
+	if filepath.IsAbs(pattern) {
+		prefix += string(filepath.Separator)
+	}
+
+	for _, segment := range splitPattern(pattern) {
+		if IsPattern(segment) {
+			break
+		}
+		prefix = filepath.Join(prefix, segment)
+	}
+
+	if prefix == "" {
+		return "."
+	}
+
+	return prefix
+}
+
+// Validate checks if all the segments of the pattern are valid
+func Validate(pattern string) error {
+	for _, segment := range splitPattern(pattern) {
+		if segment == doubleStar {
+			continue
+		}
+
+		if hasCapture(segment) {
+			if _, err := compileSegment(segment); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, err := filepath.Match(segment, ""); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Expand returns the directories that match the pattern, sorted by path, with the values of the capture groups
+// of the pattern. Directories that can't be read while expanding the pattern are skipped. "**" segments do not
+// follow symbolic links, to avoid loops, but other segments do.
+func Expand(pattern string) ([]Match, error) {
+	if err := Validate(pattern); err != nil {
+		return nil, err
+	}
+
+	root := "."
+	if filepath.IsAbs(pattern) {
+		root = filepath.VolumeName(pattern) + string(filepath.Separator)
+	}
+
+	e := &expander{seen: make(map[string]struct{}), regexps: make(map[string]*regexp.Regexp)}
+	e.expand(root, splitPattern(pattern), nil)
+
+	sort.Slice(e.matches, func(i, j int) bool {
+		return e.matches[i].Path < e.matches[j].Path
+	})
+
+	return e.matches, nil
+}
+
+// splitPattern splits the pattern into its path segments, ignoring the volume name and empty segments.
+// Separators inside capture groups, like in "(?P<name>[^/]+)", don't split the pattern.
+func splitPattern(pattern string) []string {
+	pattern = pattern[len(filepath.VolumeName(pattern)):]
+
+	segments := make([]string, 0)
+	addSegment := func(segment string) {
+		if segment != "" && segment != "." {
+			segments = append(segments, segment)
+		}
+	}
+
+	start := 0
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case pattern[i] == '(' && hasCapture(pattern[i:]):
+			if end := groupEnd(pattern, i); end > 0 {
+				i = end
+			}
+		case os.IsPathSeparator(pattern[i]):
+			addSegment(pattern[start:i])
+			start = i + 1
+		}
+	}
+	addSegment(pattern[start:])
+
+	return segments
+}
+
+// expander holds the state of a pattern expansion
+type expander struct {
+	matches []Match
+	seen    map[string]struct{}
+	// regexps holds the compiled segments with capture groups
+	regexps map[string]*regexp.Regexp
+}
+
+// expand matches the remaining segments against the contents of dir. The captures hold the values of the
+// capture groups of the segments matched so far.
+func (e *expander) expand(dir string, segments []string, captures map[string]string) {
+	if len(segments) == 0 {
+		e.addMatch(dir, captures)
+		return
+	}
+
+	segment, rest := segments[0], segments[1:]
+
+	switch {
+	case segment == doubleStar:
+		// "**" matches the current directory and any directory below it
+		e.expand(dir, rest, captures)
+		for _, subdir := range readSubdirs(dir, false) {
+			e.expand(filepath.Join(dir, subdir), segments, captures)
+		}
+	case hasCapture(segment):
+		re := e.compile(segment)
+		for _, subdir := range readSubdirs(dir, true) {
+			if values := re.FindStringSubmatch(subdir); values != nil {
+				e.expand(filepath.Join(dir, subdir), rest, withCaptures(captures, re, values))
+			}
+		}
+	case !IsPattern(segment):
+		e.expand(filepath.Join(dir, segment), rest, captures)
+	default:
+		for _, subdir := range readSubdirs(dir, true) {
+			if matched, _ := filepath.Match(segment, subdir); matched {
+				e.expand(filepath.Join(dir, subdir), rest, captures)
+			}
+		}
+	}
+}
+
+// compile returns the compiled regular expression of a segment with capture groups. The segment was already
+// validated, so it always compiles.
+func (e *expander) compile(segment string) *regexp.Regexp {
+	if re, ok := e.regexps[segment]; ok {
+		return re
+	}
+
+	re, _ := compileSegment(segment)
+	e.regexps[segment] = re
+
+	return re
+}
+
+// withCaptures returns a copy of the captures with the named groups of the regular expression match added
+func withCaptures(captures map[string]string, re *regexp.Regexp, values []string) map[string]string {
+	merged := make(map[string]string, len(captures)+len(values))
+	for name, value := range captures {
+		merged[name] = value
+	}
+
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			merged[name] = values[i]
+		}
+	}
+
+	return merged
+}
+
+// addMatch adds the path to the matches if it is a directory that was not matched before
+func (e *expander) addMatch(path string, captures map[string]string) {
+	if _, ok := e.seen[path]; ok {
+		return
+	}
+
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return
+	}
+
+	e.seen[path] = struct{}{}
+	e.matches = append(e.matches, Match{Path: path, Captures: captures})
+}
+
+// readSubdirs returns the names of the subdirectories of dir, optionally including symbolic links to directories.
+// Errors are ignored, as a directory that can't be read has no subdirectories to match.
+func readSubdirs(dir string, followSymlinks bool) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	subdirs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			subdirs = append(subdirs, entry.Name())
+			continue
+		}
+
+		if !followSymlinks || entry.Type()&os.ModeSymlink == 0 {
+			continue
+		}
+
+		if info, err := os.Stat(filepath.Join(dir, entry.Name())); err == nil && info.IsDir() {
+			subdirs = append(subdirs, entry.Name())
+		}
+	}
+
+	return subdirs
+}
@@ -0,0 +1,186 @@
+package glob_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/prom-dirsize-exporter/internal/glob"
+)
+
+// createTree creates the given directories and files under root
+func createTree(t *testing.T, root string, dirs []string, files []string) {
+	t.Helper()
+
+	for _, dir := range dirs {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, dir), 0o755))
+	}
+
+	for _, file := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(root, file), []byte("data"), 0o600))
+	}
+}
+
+func TestIsPattern(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, glob.IsPattern("/srv/*/uploads"))
+	assert.True(t, glob.IsPattern("/srv/tenant?"))
+	assert.True(t, glob.IsPattern("/srv/[ab]"))
+	assert.True(t, glob.IsPattern("/srv/**"))
+	assert.True(t, glob.IsPattern("/srv/(?P<tenant>a)"))
+	assert.False(t, glob.IsPattern("/srv/tenants"))
+}
+
+func TestStaticPrefix(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "/srv/tenants", glob.StaticPrefix("/srv/tenants/*/uploads"))
+	assert.Equal(t, "/srv", glob.StaticPrefix("/srv/**"))
+	assert.Equal(t, "/", glob.StaticPrefix("/*"))
+	assert.Equal(t, "data", glob.StaticPrefix("data/*"))
+	assert.Equal(t, ".", glob.StaticPrefix("*"))
+	assert.Equal(t, "/srv", glob.StaticPrefix("/srv/(?P<tenant>[^/]+)/data"))
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, glob.Validate("/srv/**/[ab]*"))
+	assert.ErrorIs(t, glob.Validate("/srv/[a-"), filepath.ErrBadPattern)
+	assert.NoError(t, glob.Validate("/srv/(?P<tenant>[^/]+)/data"))
+	assert.Error(t, glob.Validate("/srv/(?P<tenant>[a-)/data"))
+	assert.Error(t, glob.Validate("/srv/(?P<tenant>a/data"))
+}
+
+func TestCaptureNames(t *testing.T) {
+	t.Parallel()
+
+	names, err := glob.CaptureNames("/srv/(?P<tenant>[^/]+)/*/env-(?<env>prod|staging)")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tenant", "env"}, names)
+
+	names, err = glob.CaptureNames("/srv/*/data")
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
+
+func TestExpand(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	createTree(t, root,
+		[]string{
+			"tenants/a/uploads",
+			"tenants/b/uploads",
+			"tenants/c/other",
+			"tenants/d/nested/uploads",
+		},
+		[]string{
+			"tenants/file",
+			"tenants/c/uploads",
+		},
+	)
+
+	scenarios := []struct {
+		name     string
+		pattern  string
+		expected []string
+	}{
+		{
+			name:     "Single wildcard",
+			pattern:  "tenants/*/uploads",
+			expected: []string{"tenants/a/uploads", "tenants/b/uploads"},
+		},
+		{
+			name:     "Only matches directories",
+			pattern:  "tenants/*",
+			expected: []string{"tenants/a", "tenants/b", "tenants/c", "tenants/d"},
+		},
+		{
+			name:     "Double star",
+			pattern:  "tenants/**/uploads",
+			expected: []string{"tenants/a/uploads", "tenants/b/uploads", "tenants/d/nested/uploads"},
+		},
+		{
+			name:     "Character class",
+			pattern:  "tenants/[ab]",
+			expected: []string{"tenants/a", "tenants/b"},
+		},
+		{
+			name:     "No matches",
+			pattern:  "tenants/*/missing",
+			expected: nil,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+
+			matches, err := glob.Expand(filepath.Join(root, scenario.pattern))
+			require.NoError(t, err)
+
+			var expected []glob.Match
+			for _, path := range scenario.expected {
+				expected = append(expected, glob.Match{Path: filepath.Join(root, path)})
+			}
+
+			assert.Equal(t, expected, matches)
+		})
+	}
+}
+
+func TestExpand_WithInvalidPattern_ReturnsError(t *testing.T) {
+	t.Parallel()
+
+	_, err := glob.Expand("/srv/[a-")
+
+	assert.ErrorIs(t, err, filepath.ErrBadPattern)
+}
+
+func TestExpand_WithCaptureGroups_ReturnsCaptures(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	createTree(t, root,
+		[]string{
+			"srv/acme/env-prod/data",
+			"srv/acme/env-dev/data",
+			"srv/globex/env-staging/data",
+			"srv/globex/env-prod/other",
+		},
+		nil,
+	)
+
+	matches, err := glob.Expand(filepath.Join(root, "srv/(?P<tenant>[^/]+)/env-(?P<env>prod|staging)/data"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []glob.Match{
+		{
+			Path:     filepath.Join(root, "srv/acme/env-prod/data"),
+			Captures: map[string]string{"tenant": "acme", "env": "prod"},
+		},
+		{
+			Path:     filepath.Join(root, "srv/globex/env-staging/data"),
+			Captures: map[string]string{"tenant": "globex", "env": "staging"},
+		},
+	}, matches)
+}
+
+func TestExpand_WithGlobInCaptureSegment_MatchesWholeName(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	createTree(t, root, []string{"app-1.log.d", "app-2.logxd", "app-3.log.d.old"}, nil)
+
+	matches, err := glob.Expand(filepath.Join(root, "app-(?P<id>[0-9]+).log.*"))
+	require.NoError(t, err)
+
+	require.Len(t, matches, 2)
+	assert.Equal(t, map[string]string{"id": "1"}, matches[0].Captures)
+	assert.Equal(t, map[string]string{"id": "3"}, matches[1].Captures)
+}
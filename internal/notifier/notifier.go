@@ -0,0 +1,41 @@
+// Package notifier sends the alerts raised by the directory scans to external systems.
+package notifier
+
+import (
+	"context"
+	"time"
+)
+
+// Alert types
+const (
+	// AlertTypeQuota is raised when the quota status of a directory changes
+	AlertTypeQuota = "quota"
+	// AlertTypeGrowth is raised when a directory grows more than the configured percentage between scans
+	AlertTypeGrowth = "growth"
+)
+
+// Alert is an event raised by the scan of a directory
+type Alert struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// Status is the new quota status of the directory, only set for quota alerts
+	Status string `json:"status,omitempty"`
+	// PreviousStatus is the quota status before the scan, only set for quota alerts
+	PreviousStatus    string    `json:"previous_status,omitempty"`
+	SizeBytes         int64     `json:"size_bytes"`
+	PreviousSizeBytes int64     `json:"previous_size_bytes"`
+	Files             int64     `json:"files"`
+	GrowthPercent     float64   `json:"growth_percent"`
+	Timestamp         time.Time `json:"timestamp"`
+}
+
+// key identifies the alerts of the same type raised for the same directory
+func (a Alert) key() string {
+	return a.Type + "\x00" + a.Path
+}
+
+// Notifier sends alerts
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
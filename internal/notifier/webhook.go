@@ -0,0 +1,207 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/brpaz/prom-dirsize-exporter/internal/config"
+)
+
+const (
+	// DefaultRetries is the default number of times a failed request is retried
+	DefaultRetries = config.DefaultWebhookRetries
+	// DefaultBackoff is the default wait before the first retry, doubled on every retry
+	DefaultBackoff = time.Second
+	// DefaultRepeatInterval is the default time during which repeated alerts are not sent again
+	DefaultRepeatInterval = config.DefaultWebhookRepeatInterval
+	// DefaultTimeout is the default timeout of each request
+	DefaultTimeout = 10 * time.Second
+)
+
+// Webhook sends alerts as JSON payloads in POST requests to a URL.
+// Failed requests are retried with an exponential backoff, and alerts that were already sent for the same
+// directory and status are not sent again within the repeat interval, unless another status was sent in between.
+type Webhook struct {
+	url            string
+	logger         *zap.Logger
+	client         *http.Client
+	retries        int
+	backoff        time.Duration
+	repeatInterval time.Duration
+
+	mutex sync.Mutex
+	// sent holds the last alert sent of each type for each directory, indexed by its key
+	sent map[string]sentAlert
+}
+
+// sentAlert holds the status of an alert that was sent, and when
+type sentAlert struct {
+	status string
+	sentAt time.Time
+}
+
+// WebhookOption represents an option to customize Webhook behavior
+type WebhookOption func(*Webhook)
+
+// WithLogger sets the logger of the Webhook
+func WithLogger(logger *zap.Logger) WebhookOption {
+	return func(w *Webhook) {
+		w.logger = logger
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send the requests
+func WithHTTPClient(client *http.Client) WebhookOption {
+	return func(w *Webhook) {
+		w.client = client
+	}
+}
+
+// WithRetries sets the number of times a failed request is retried
+func WithRetries(retries int) WebhookOption {
+	return func(w *Webhook) {
+		w.retries = retries
+	}
+}
+
+// WithBackoff sets the wait before the first retry, that is doubled on every retry
+func WithBackoff(backoff time.Duration) WebhookOption {
+	return func(w *Webhook) {
+		w.backoff = backoff
+	}
+}
+
+// WithRepeatInterval sets the time during which repeated alerts are not sent again
+func WithRepeatInterval(interval time.Duration) WebhookOption {
+	return func(w *Webhook) {
+		w.repeatInterval = interval
+	}
+}
+
+// NewWebhook creates a new Webhook that sends the alerts to the URL, with the provided options
+func NewWebhook(url string, opts ...WebhookOption) *Webhook {
+	w := &Webhook{
+		url:            url,
+		logger:         zap.NewNop(),
+		client:         &http.Client{Timeout: DefaultTimeout},
+		retries:        DefaultRetries,
+		backoff:        DefaultBackoff,
+		repeatInterval: DefaultRepeatInterval,
+		sent:           make(map[string]sentAlert),
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w
+}
+
+// Notify sends the alert, unless the same alert was sent within the repeat interval.
+// It blocks until the alert is sent, all the retries fail or the context is cancelled.
+func (w *Webhook) Notify(ctx context.Context, alert Alert) error {
+	if w.isRepeated(alert) {
+		w.logger.Debug("skipping repeated alert", zap.String("type", alert.Type), zap.String("directory", alert.Path))
+		return nil
+	}
+
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("error encoding alert: %w", err)
+	}
+
+	backoff := w.backoff
+	for attempt := 0; ; attempt++ {
+		err = w.send(ctx, payload)
+		if err == nil {
+			w.markSent(alert)
+			return nil
+		}
+
+		if attempt >= w.retries || !isRetryable(err) {
+			return err
+		}
+
+		w.logger.Warn("failed to send alert, retrying",
+			zap.String("directory", alert.Path),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
+
+// isRepeated returns true if the last alert sent for the directory had the same status, within the repeat
+// interval. A status that flaps back, like "soft" to "ok" and "soft" again, is sent every time it changes.
+func (w *Webhook) isRepeated(alert Alert) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	sent, ok := w.sent[alert.key()]
+
+	return ok && sent.status == alert.Status && time.Since(sent.sentAt) < w.repeatInterval
+}
+
+// markSent records that the alert was sent
+func (w *Webhook) markSent(alert Alert) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.sent[alert.key()] = sentAlert{status: alert.Status, sentAt: time.Now()}
+}
+
+// statusError is returned when the webhook responds with an unexpected status code
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("webhook responded with status %d", e.code)
+}
+
+// isRetryable returns true if the request might succeed if sent again. Client errors, other than rate
+// limiting, are not retried.
+func isRetryable(err error) bool {
+	var statusErr *statusError
+	if !errors.As(err, &statusErr) {
+		return true
+	}
+
+	return statusErr.code >= http.StatusInternalServerError || statusErr.code == http.StatusTooManyRequests
+}
+
+// send makes a single request with the payload
+func (w *Webhook) send(ctx context.Context, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("error creating webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending webhook request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &statusError{code: resp.StatusCode}
+	}
+
+	return nil
+}
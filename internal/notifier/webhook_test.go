@@ -0,0 +1,152 @@
+package notifier_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/prom-dirsize-exporter/internal/notifier"
+)
+
+// newWebhookServer starts a test server that responds with the given status codes in order, repeating the last one,
+// and returns it with the counter of received requests and a channel with the received alerts
+func newWebhookServer(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32, chan notifier.Alert) {
+	t.Helper()
+
+	var requests atomic.Int32
+	alerts := make(chan notifier.Alert, 10)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(requests.Add(1))
+
+		var alert notifier.Alert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err == nil {
+			alerts <- alert
+		}
+
+		w.WriteHeader(statuses[min(n, len(statuses))-1])
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &requests, alerts
+}
+
+func TestWebhook_Notify_PostsAlert(t *testing.T) {
+	t.Parallel()
+
+	srv, _, alerts := newWebhookServer(t, http.StatusOK)
+
+	alert := notifier.Alert{Type: notifier.AlertTypeQuota, Name: "logs", Path: "/var/log", Status: "soft", SizeBytes: 2000}
+	require.NoError(t, notifier.NewWebhook(srv.URL).Notify(context.Background(), alert))
+
+	received := <-alerts
+	assert.Equal(t, alert.Path, received.Path)
+	assert.Equal(t, alert.Status, received.Status)
+	assert.Equal(t, alert.SizeBytes, received.SizeBytes)
+}
+
+func TestWebhook_Notify_RetriesServerErrors(t *testing.T) {
+	t.Parallel()
+
+	srv, requests, _ := newWebhookServer(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK)
+
+	webhook := notifier.NewWebhook(srv.URL, notifier.WithBackoff(time.Millisecond))
+
+	require.NoError(t, webhook.Notify(context.Background(), notifier.Alert{Path: "/var/log"}))
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func TestWebhook_Notify_GivesUpAfterRetries(t *testing.T) {
+	t.Parallel()
+
+	srv, requests, _ := newWebhookServer(t, http.StatusServiceUnavailable)
+
+	webhook := notifier.NewWebhook(srv.URL, notifier.WithBackoff(time.Millisecond), notifier.WithRetries(2))
+
+	assert.ErrorContains(t, webhook.Notify(context.Background(), notifier.Alert{Path: "/var/log"}), "status 503")
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func TestWebhook_Notify_DoesNotRetryClientErrors(t *testing.T) {
+	t.Parallel()
+
+	srv, requests, _ := newWebhookServer(t, http.StatusBadRequest)
+
+	webhook := notifier.NewWebhook(srv.URL, notifier.WithBackoff(time.Millisecond))
+
+	assert.Error(t, webhook.Notify(context.Background(), notifier.Alert{Path: "/var/log"}))
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestWebhook_Notify_DeduplicatesRepeatedAlerts(t *testing.T) {
+	t.Parallel()
+
+	srv, requests, _ := newWebhookServer(t, http.StatusOK)
+
+	webhook := notifier.NewWebhook(srv.URL)
+	alert := notifier.Alert{Type: notifier.AlertTypeQuota, Path: "/var/log", Status: "soft"}
+
+	require.NoError(t, webhook.Notify(context.Background(), alert))
+	require.NoError(t, webhook.Notify(context.Background(), alert))
+	assert.Equal(t, int32(1), requests.Load())
+
+	alert.Status = "hard"
+	require.NoError(t, webhook.Notify(context.Background(), alert))
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestWebhook_Notify_SendsQuotaStatusFlaps(t *testing.T) {
+	t.Parallel()
+
+	srv, requests, _ := newWebhookServer(t, http.StatusOK)
+
+	webhook := notifier.NewWebhook(srv.URL)
+	transitions := []struct{ previous, status string }{{"ok", "soft"}, {"soft", "ok"}, {"ok", "soft"}}
+
+	for _, transition := range transitions {
+		alert := notifier.Alert{
+			Type:           notifier.AlertTypeQuota,
+			Path:           "/var/log",
+			PreviousStatus: transition.previous,
+			Status:         transition.status,
+		}
+		require.NoError(t, webhook.Notify(context.Background(), alert))
+	}
+
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func TestWebhook_Notify_SendsAgainAfterRepeatInterval(t *testing.T) {
+	t.Parallel()
+
+	srv, requests, _ := newWebhookServer(t, http.StatusOK)
+
+	webhook := notifier.NewWebhook(srv.URL, notifier.WithRepeatInterval(10*time.Millisecond))
+	alert := notifier.Alert{Type: notifier.AlertTypeGrowth, Path: "/var/log"}
+
+	require.NoError(t, webhook.Notify(context.Background(), alert))
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, webhook.Notify(context.Background(), alert))
+
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestWebhook_Notify_WithCancelledContext_StopsRetrying(t *testing.T) {
+	t.Parallel()
+
+	srv, _, _ := newWebhookServer(t, http.StatusServiceUnavailable)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	webhook := notifier.NewWebhook(srv.URL, notifier.WithBackoff(time.Hour))
+
+	assert.Error(t, webhook.Notify(ctx, notifier.Alert{Path: "/var/log"}))
+}
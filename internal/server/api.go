@@ -0,0 +1,274 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/brpaz/prom-dirsize-exporter/internal/collector"
+	"github.com/brpaz/prom-dirsize-exporter/internal/walker"
+)
+
+// entryResponse is the JSON representation of a file or directory
+type entryResponse struct {
+	Path      string `json:"path"`
+	SizeBytes int64  `json:"size_bytes"`
+}
+
+// largestEntriesResponse is the JSON response of the largest entries of a directory
+type largestEntriesResponse struct {
+	Name        string          `json:"name"`
+	Path        string          `json:"path"`
+	ScannedAt   time.Time       `json:"scanned_at"`
+	Files       []entryResponse `json:"files"`
+	Directories []entryResponse `json:"directories"`
+}
+
+// directoryResponse is the JSON representation of the cached state of a directory
+type directoryResponse struct {
+	Name                string  `json:"name"`
+	Path                string  `json:"path"`
+	SizeBytes           int64   `json:"size_bytes"`
+	DiskUsageBytes      int64   `json:"disk_usage_bytes"`
+	Files               int64   `json:"files"`
+	Subdirectories      int64   `json:"subdirectories"`
+	ScanDurationSeconds float64 `json:"scan_duration_seconds"`
+	// LastScan is null when the directory was never scanned successfully
+	LastScan *time.Time `json:"last_scan"`
+	// Error is empty when the last scan succeeded
+	Error string `json:"error"`
+}
+
+// directoriesResponse is the JSON response of the list of directories
+type directoriesResponse struct {
+	Directories []directoryResponse `json:"directories"`
+}
+
+// Values of the status of the scan responses
+const (
+	scanStatusQueued    = "queued"
+	scanStatusCompleted = "completed"
+)
+
+// scanResponse is the JSON response of a rescan request of a directory
+type scanResponse struct {
+	Status string `json:"status"`
+}
+
+// scanAllResponse is the JSON response of a rescan request of all the directories, with the paths of the
+// directories that were queued and skipped because they were already being scanned
+type scanAllResponse struct {
+	Status  string   `json:"status"`
+	Queued  []string `json:"queued"`
+	Skipped []string `json:"skipped"`
+}
+
+// errorResponse is the JSON response returned when a request fails
+type errorResponse struct {
+	Error string `json:"error"`
+	// Paths holds the paths of the directories with the requested name, when there are several
+	Paths []string `json:"paths,omitempty"`
+}
+
+// handleDirectories returns the cached state of all the directories
+func (s *MetricsServer) handleDirectories(w http.ResponseWriter, _ *http.Request) {
+	summaries := s.collector.Summaries()
+
+	response := directoriesResponse{Directories: make([]directoryResponse, 0, len(summaries))}
+	for _, summary := range summaries {
+		response.Directories = append(response.Directories, toDirectoryResponse(summary))
+	}
+
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+// largestEntriesSuffix is the suffix of the path of the largest entries of a directory
+const largestEntriesSuffix = "/top"
+
+// handleDirectory returns the cached state of a directory, or its largest entries when the path ends with "/top"
+func (s *MetricsServer) handleDirectory(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if entriesName, ok := strings.CutSuffix(name, largestEntriesSuffix); ok {
+		s.handleLargestEntries(w, entriesName)
+		return
+	}
+
+	summary, err := s.collector.Summary(name)
+	if err != nil {
+		s.writeLookupError(w, name, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, toDirectoryResponse(summary))
+}
+
+// toDirectoryResponse converts the summary of a directory to its JSON representation
+func toDirectoryResponse(summary collector.DirectorySummary) directoryResponse {
+	response := directoryResponse{
+		Name:                summary.Name,
+		Path:                summary.Path,
+		SizeBytes:           summary.Size,
+		DiskUsageBytes:      summary.DiskUsage,
+		Files:               summary.Files,
+		Subdirectories:      summary.Subdirectories,
+		ScanDurationSeconds: summary.ScanDuration.Seconds(),
+		Error:               summary.Error,
+	}
+
+	if !summary.LastScan.IsZero() {
+		lastScan := summary.LastScan
+		response.LastScan = &lastScan
+	}
+
+	return response
+}
+
+// scanSuffix is the suffix of the path to request a scan of a directory
+const scanSuffix = "/scan"
+
+// handleScan queues an immediate scan of a directory, for paths ending with "/scan". With the "wait" query
+// parameter set to true, the response is only sent once the scan finishes.
+func (s *MetricsServer) handleScan(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutSuffix(r.PathValue("name"), scanSuffix)
+	if !ok {
+		s.writeJSON(w, http.StatusNotFound, errorResponse{Error: "not found"})
+		return
+	}
+
+	wait, err := waitParam(r)
+	if err != nil {
+		s.writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	done, err := s.collector.Rescan(name)
+	switch {
+	case errors.Is(err, collector.ErrScanInProgress):
+		s.writeJSON(w, http.StatusConflict, errorResponse{Error: "directory " + name + " is already being scanned"})
+		return
+	case err != nil:
+		s.writeLookupError(w, name, err)
+		return
+	}
+
+	s.logger.Info("rescan queued", zap.String("name", name), zap.Bool("wait", wait))
+
+	if !wait {
+		s.writeJSON(w, http.StatusAccepted, scanResponse{Status: scanStatusQueued})
+		return
+	}
+
+	if s.waitForScan(r, done) {
+		s.writeJSON(w, http.StatusOK, scanResponse{Status: scanStatusCompleted})
+	}
+}
+
+// handleScanAll queues an immediate scan of all the directories, skipping the ones already being scanned.
+// With the "wait" query parameter set to true, the response is only sent once all the scans finish.
+func (s *MetricsServer) handleScanAll(w http.ResponseWriter, r *http.Request) {
+	wait, err := waitParam(r)
+	if err != nil {
+		s.writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	done, queued, skipped := s.collector.RescanAll()
+
+	s.logger.Info("rescan of all directories queued",
+		zap.Int("queued", len(queued)),
+		zap.Int("skipped", len(skipped)),
+		zap.Bool("wait", wait),
+	)
+
+	if !wait {
+		s.writeJSON(w, http.StatusAccepted, scanAllResponse{Status: scanStatusQueued, Queued: queued, Skipped: skipped})
+		return
+	}
+
+	if s.waitForScan(r, done) {
+		s.writeJSON(w, http.StatusOK, scanAllResponse{Status: scanStatusCompleted, Queued: queued, Skipped: skipped})
+	}
+}
+
+// writeLookupError writes the error of looking up a directory by name: 404 when it's not found, and 409 with the
+// paths of the directories when the name is ambiguous
+func (s *MetricsServer) writeLookupError(w http.ResponseWriter, name string, err error) {
+	var ambiguous *collector.AmbiguousNameError
+
+	switch {
+	case errors.Is(err, collector.ErrDirectoryNotFound):
+		s.writeJSON(w, http.StatusNotFound, errorResponse{Error: "directory " + name + " not found or not scanned yet"})
+	case errors.As(err, &ambiguous):
+		s.writeJSON(w, http.StatusConflict, errorResponse{Error: err.Error(), Paths: ambiguous.Paths})
+	default:
+		s.writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+	}
+}
+
+// waitParam returns the value of the "wait" query parameter, false when it's not set
+func waitParam(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("wait")
+	if value == "" {
+		return false, nil
+	}
+
+	wait, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid wait parameter %q, must be true or false", value)
+	}
+
+	return wait, nil
+}
+
+// waitForScan blocks until the scan is done, returning false if the request is cancelled first
+func (s *MetricsServer) waitForScan(r *http.Request, done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// handleLargestEntries returns the largest files and subdirectories of a directory
+func (s *MetricsServer) handleLargestEntries(w http.ResponseWriter, name string) {
+	entries, err := s.collector.LargestEntries(name)
+	if err != nil {
+		s.writeLookupError(w, name, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, largestEntriesResponse{
+		Name:        entries.Name,
+		Path:        entries.Path,
+		ScannedAt:   entries.ScannedAt,
+		Files:       toEntryResponses(entries.Files),
+		Directories: toEntryResponses(entries.Directories),
+	})
+}
+
+// toEntryResponses converts the walker entries to their JSON representation
+func toEntryResponses(entries []walker.Entry) []entryResponse {
+	response := make([]entryResponse, 0, len(entries))
+	for _, entry := range entries {
+		response = append(response, entryResponse{Path: entry.Path, SizeBytes: entry.Size})
+	}
+
+	return response
+}
+
+// writeJSON writes the value as a JSON response with the given status code
+func (s *MetricsServer) writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		s.logger.Error("failed to write JSON response", zap.Error(err))
+	}
+}
@@ -0,0 +1,477 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/brpaz/prom-dirsize-exporter/internal/collector"
+	"github.com/brpaz/prom-dirsize-exporter/internal/config"
+	"github.com/brpaz/prom-dirsize-exporter/internal/server"
+	"github.com/brpaz/prom-dirsize-exporter/internal/testutil"
+)
+
+// startServer starts a MetricsServer with the given options on a free port and returns its base URL
+func startServer(t *testing.T, opts ...server.MetricsServerOption) string {
+	t.Helper()
+
+	port, err := testutil.GetFreePort()
+	if err != nil {
+		t.Fatalf("Error getting free port: %s", err)
+	}
+
+	opts = append([]server.MetricsServerOption{
+		server.WithLogger(zap.NewNop()),
+		server.WithListenAddresses(fmt.Sprintf(":%d", port)),
+	}, opts...)
+	srv := server.NewMetricsServer(opts...)
+
+	go func() {
+		_ = srv.Start()
+	}()
+
+	t.Cleanup(func() {
+		_ = srv.Stop()
+	})
+
+	// Wait for a short time to allow the server to start.
+	time.Sleep(100 * time.Millisecond)
+
+	return fmt.Sprintf("http://localhost:%d", port)
+}
+
+// startCollector starts a collector for the targets and waits until they are scanned
+func startCollector(t *testing.T, targets []config.Directory) *collector.DirectoryCollector {
+	t.Helper()
+
+	c := collector.NewDirectoryCollector(collector.WithTargets(targets))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		for _, target := range targets {
+			if _, err := c.LargestEntries(target.LabelName()); err != nil {
+				return false
+			}
+		}
+
+		return true
+	}, time.Second, 10*time.Millisecond)
+
+	return c
+}
+
+func TestAPI_LargestEntries(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "videos"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "videos", "movie.mp4"), make([]byte, 5000), 0o600))
+
+	c := startCollector(t, []config.Directory{{Path: root, Name: "media", TopEntries: 5}})
+	baseURL := startServer(t, server.WithCollector(c))
+
+	resp, err := http.Get(baseURL + "/api/v1/directories/media/top")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var body struct {
+		Name  string `json:"name"`
+		Path  string `json:"path"`
+		Files []struct {
+			Path      string `json:"path"`
+			SizeBytes int64  `json:"size_bytes"`
+		} `json:"files"`
+		Directories []struct {
+			Path string `json:"path"`
+		} `json:"directories"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+	assert.Equal(t, "media", body.Name)
+	assert.Equal(t, root, body.Path)
+	require.Len(t, body.Files, 1)
+	assert.Equal(t, "videos/movie.mp4", body.Files[0].Path)
+	assert.Equal(t, int64(5000), body.Files[0].SizeBytes)
+	require.Len(t, body.Directories, 1)
+	assert.Equal(t, "videos", body.Directories[0].Path)
+}
+
+func TestAPI_LargestEntries_WithGlobPattern(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "t1", "uploads"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "t1", "uploads", "file"), make([]byte, 100), 0o600))
+
+	c := collector.NewDirectoryCollector(
+		collector.WithTargets([]config.Directory{{Path: filepath.Join(root, "*", "uploads"), TopEntries: 5}}),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return c.Readiness().Ready()
+	}, time.Second, 10*time.Millisecond)
+
+	baseURL := startServer(t, server.WithCollector(c))
+
+	resp, err := http.Get(baseURL + "/api/v1/directories/t1/uploads/top")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var body struct {
+		Name  string `json:"name"`
+		Files []struct {
+			Path string `json:"path"`
+		} `json:"files"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+	assert.Equal(t, "t1/uploads", body.Name)
+	require.Len(t, body.Files, 1)
+	assert.Equal(t, "file", body.Files[0].Path)
+}
+
+func TestAPI_LargestEntries_WithUnknownDirectory_ReturnsNotFound(t *testing.T) {
+	t.Parallel()
+
+	c := startCollector(t, []config.Directory{{Path: t.TempDir(), Name: "media"}})
+	baseURL := startServer(t, server.WithCollector(c))
+
+	resp, err := http.Get(baseURL + "/api/v1/directories/unknown/top")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+// directoryBody is the decoded JSON representation of a directory
+type directoryBody struct {
+	Name                string     `json:"name"`
+	Path                string     `json:"path"`
+	SizeBytes           int64      `json:"size_bytes"`
+	DiskUsageBytes      int64      `json:"disk_usage_bytes"`
+	Files               int64      `json:"files"`
+	Subdirectories      int64      `json:"subdirectories"`
+	ScanDurationSeconds float64    `json:"scan_duration_seconds"`
+	LastScan            *time.Time `json:"last_scan"`
+	Error               string     `json:"error"`
+}
+
+func TestAPI_Directories(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "videos"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "videos", "movie.mp4"), make([]byte, 5000), 0o600))
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	c := collector.NewDirectoryCollector(collector.WithTargets([]config.Directory{
+		{Path: root, Name: "media"},
+		{Path: missing, Name: "backups"},
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return c.Readiness().Ready()
+	}, time.Second, 10*time.Millisecond)
+
+	baseURL := startServer(t, server.WithCollector(c))
+
+	resp, err := http.Get(baseURL + "/api/v1/directories")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var body struct {
+		Directories []directoryBody `json:"directories"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.Len(t, body.Directories, 2)
+
+	backups := body.Directories[0]
+	assert.Equal(t, "backups", backups.Name)
+	assert.Equal(t, missing, backups.Path)
+	assert.Nil(t, backups.LastScan)
+	assert.Contains(t, backups.Error, "no such file or directory")
+
+	media := body.Directories[1]
+	assert.Equal(t, "media", media.Name)
+	assert.Equal(t, root, media.Path)
+	assert.Equal(t, int64(1), media.Files)
+	assert.Equal(t, int64(1), media.Subdirectories)
+	assert.GreaterOrEqual(t, media.SizeBytes, int64(5000))
+	require.NotNil(t, media.LastScan)
+	assert.WithinDuration(t, time.Now(), *media.LastScan, 5*time.Second)
+	assert.Empty(t, media.Error)
+}
+
+func TestAPI_Directory(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "a", "uploads"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a", "uploads", "file"), make([]byte, 100), 0o600))
+
+	c := collector.NewDirectoryCollector(collector.WithDirectories([]string{filepath.Join(root, "*", "uploads")}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return c.Readiness().Ready()
+	}, time.Second, 10*time.Millisecond)
+
+	baseURL := startServer(t, server.WithCollector(c))
+
+	resp, err := http.Get(baseURL + "/api/v1/directories/a/uploads")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var body directoryBody
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+	assert.Equal(t, "a/uploads", body.Name)
+	assert.Equal(t, filepath.Join(root, "a", "uploads"), body.Path)
+	assert.Equal(t, int64(1), body.Files)
+	require.NotNil(t, body.LastScan)
+}
+
+func TestAPI_Directory_WithUnknownDirectory_ReturnsNotFound(t *testing.T) {
+	t.Parallel()
+
+	c := startCollector(t, []config.Directory{{Path: t.TempDir(), Name: "media"}})
+	baseURL := startServer(t, server.WithCollector(c))
+
+	resp, err := http.Get(baseURL + "/api/v1/directories/unknown")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+// postScan requests a rescan, retrying while the collector is still busy with a previous scan
+func postScan(t *testing.T, url string) *http.Response {
+	t.Helper()
+
+	var resp *http.Response
+	require.Eventually(t, func() bool {
+		var err error
+		resp, err = http.Post(url, "", nil)
+		require.NoError(t, err)
+
+		if resp.StatusCode == http.StatusConflict {
+			resp.Body.Close()
+			return false
+		}
+
+		return true
+	}, time.Second, 10*time.Millisecond)
+
+	t.Cleanup(func() {
+		resp.Body.Close()
+	})
+
+	return resp
+}
+
+func TestAPI_Scan(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	c := startCollector(t, []config.Directory{{Path: dir, Name: "media"}})
+	baseURL := startServer(t, server.WithCollector(c))
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "Queued", query: "", expectedStatus: http.StatusAccepted, expectedBody: "queued"},
+		{name: "Wait", query: "?wait=true", expectedStatus: http.StatusOK, expectedBody: "completed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := postScan(t, baseURL+"/api/v1/directories/media/scan"+tt.query)
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			var body struct {
+				Status string `json:"status"`
+			}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, tt.expectedBody, body.Status)
+		})
+	}
+}
+
+func TestAPI_Scan_WithGlobPattern(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "t1", "uploads"), 0o755))
+
+	c := collector.NewDirectoryCollector(collector.WithDirectories([]string{filepath.Join(root, "*", "uploads")}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return c.Readiness().Ready()
+	}, time.Second, 10*time.Millisecond)
+
+	before, err := c.Summary("t1/uploads")
+	require.NoError(t, err)
+
+	baseURL := startServer(t, server.WithCollector(c))
+
+	resp := postScan(t, baseURL+"/api/v1/directories/t1/uploads/scan?wait=true")
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	after, err := c.Summary("t1/uploads")
+	require.NoError(t, err)
+	assert.True(t, after.LastScan.After(before.LastScan))
+}
+
+func TestAPI_Scan_WithInvalidRequest(t *testing.T) {
+	t.Parallel()
+
+	c := startCollector(t, []config.Directory{{Path: t.TempDir(), Name: "media"}})
+	baseURL := startServer(t, server.WithCollector(c))
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{name: "UnknownDirectory", path: "/api/v1/directories/unknown/scan", expectedStatus: http.StatusNotFound},
+		{name: "InvalidWait", path: "/api/v1/directories/media/scan?wait=maybe", expectedStatus: http.StatusBadRequest},
+		{name: "UnknownAction", path: "/api/v1/directories/media/other", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(baseURL+tt.path, "", nil)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+		})
+	}
+}
+
+func TestAPI_ScanAll(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	c := startCollector(t, []config.Directory{{Path: dir, Name: "media"}})
+	baseURL := startServer(t, server.WithCollector(c))
+
+	var body struct {
+		Status  string   `json:"status"`
+		Queued  []string `json:"queued"`
+		Skipped []string `json:"skipped"`
+	}
+	require.Eventually(t, func() bool {
+		resp, err := http.Post(baseURL+"/api/v1/directories/scan?wait=true", "", nil)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+		return len(body.Queued) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, "completed", body.Status)
+	assert.Equal(t, []string{dir}, body.Queued)
+	assert.Empty(t, body.Skipped)
+}
+
+func TestAPI_WithAmbiguousName_ReturnsConflict(t *testing.T) {
+	t.Parallel()
+
+	srv, backups := t.TempDir(), t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(srv, "a", "data"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(backups, "a", "data"), 0o755))
+
+	c := collector.NewDirectoryCollector(
+		collector.WithDirectories([]string{filepath.Join(srv, "*", "data"), filepath.Join(backups, "*", "data")}),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return c.Readiness().Ready()
+	}, time.Second, 10*time.Millisecond)
+
+	baseURL := startServer(t, server.WithCollector(c))
+
+	expected := []string{filepath.Join(srv, "a", "data"), filepath.Join(backups, "a", "data")}
+	sort.Strings(expected)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{name: "Directory", method: http.MethodGet, path: "/api/v1/directories/a/data"},
+		{name: "LargestEntries", method: http.MethodGet, path: "/api/v1/directories/a/data/top"},
+		{name: "Scan", method: http.MethodPost, path: "/api/v1/directories/a/data/scan"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, baseURL+tt.path, nil)
+			require.NoError(t, err)
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+			var body struct {
+				Paths []string `json:"paths"`
+			}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, expected, body.Paths)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"net/http"
+	"time"
+)
+
+// Values of the status of the readiness response
+const (
+	statusReady    = "ready"
+	statusNotReady = "not_ready"
+)
+
+// directoryStatusResponse is the JSON representation of a directory whose last scan failed
+type directoryStatusResponse struct {
+	Name  string `json:"name"`
+	Path  string `json:"path"`
+	Error string `json:"error"`
+	// LastSuccess is omitted when the directory was never scanned successfully
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+}
+
+// readinessResponse is the JSON response of the readiness endpoint
+type readinessResponse struct {
+	Status  string                    `json:"status"`
+	Pending []string                  `json:"pending"`
+	Failing []directoryStatusResponse `json:"failing"`
+}
+
+// handleHealthy reports that the exporter is alive, as long as it can serve requests
+func (s *MetricsServer) handleHealthy(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("Healthy"))
+}
+
+// handleReady reports whether all the directories were scanned at least once, with the directories that are
+// still pending and the ones whose last scan failed. It returns 503 until all the directories are scanned.
+func (s *MetricsServer) handleReady(w http.ResponseWriter, _ *http.Request) {
+	if s.collector == nil {
+		s.writeJSON(w, http.StatusOK, readinessResponse{Status: statusReady, Pending: []string{}, Failing: []directoryStatusResponse{}})
+		return
+	}
+
+	readiness := s.collector.Readiness()
+
+	response := readinessResponse{
+		Status:  statusReady,
+		Pending: readiness.Pending,
+		Failing: make([]directoryStatusResponse, 0, len(readiness.Failing)),
+	}
+
+	for _, directory := range readiness.Failing {
+		status := directoryStatusResponse{Name: directory.Name, Path: directory.Path, Error: directory.Error}
+		if !directory.LastSuccess.IsZero() {
+			lastSuccess := directory.LastSuccess
+			status.LastSuccess = &lastSuccess
+		}
+		response.Failing = append(response.Failing, status)
+	}
+
+	code := http.StatusOK
+	if !readiness.Ready() {
+		response.Status = statusNotReady
+		code = http.StatusServiceUnavailable
+	}
+
+	s.writeJSON(w, code, response)
+}
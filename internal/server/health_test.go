@@ -0,0 +1,119 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/prom-dirsize-exporter/internal/collector"
+	"github.com/brpaz/prom-dirsize-exporter/internal/config"
+	"github.com/brpaz/prom-dirsize-exporter/internal/server"
+)
+
+// readinessBody is the decoded response of the readiness endpoint
+type readinessBody struct {
+	Status  string   `json:"status"`
+	Pending []string `json:"pending"`
+	Failing []struct {
+		Name        string  `json:"name"`
+		Path        string  `json:"path"`
+		Error       string  `json:"error"`
+		LastSuccess *string `json:"last_success"`
+	} `json:"failing"`
+}
+
+// getReadiness requests the readiness endpoint and returns its status code and decoded body
+func getReadiness(t *testing.T, baseURL string) (int, readinessBody) {
+	t.Helper()
+
+	resp, err := http.Get(baseURL + "/-/ready")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var body readinessBody
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+	return resp.StatusCode, body
+}
+
+func TestHealthy_ReturnsOK(t *testing.T) {
+	t.Parallel()
+
+	baseURL := startServer(t)
+
+	resp, err := http.Get(baseURL + "/-/healthy")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "Healthy", string(body))
+}
+
+func TestReady_BeforeFirstScan_ReturnsServiceUnavailable(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	c := collector.NewDirectoryCollector(collector.WithDirectories([]string{dir}))
+	baseURL := startServer(t, server.WithCollector(c))
+
+	status, body := getReadiness(t, baseURL)
+
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, "not_ready", body.Status)
+	assert.Equal(t, []string{dir}, body.Pending)
+	assert.Empty(t, body.Failing)
+}
+
+func TestReady_AfterFirstScan_ReportsFailingDirectories(t *testing.T) {
+	t.Parallel()
+
+	missing := filepath.Join(t.TempDir(), "missing")
+	c := collector.NewDirectoryCollector(collector.WithTargets([]config.Directory{
+		{Path: t.TempDir(), Name: "media"},
+		{Path: missing, Name: "missing"},
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	c.Start(ctx)
+
+	baseURL := startServer(t, server.WithCollector(c))
+
+	require.Eventually(t, func() bool {
+		return c.Readiness().Ready()
+	}, time.Second, 10*time.Millisecond)
+
+	status, body := getReadiness(t, baseURL)
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "ready", body.Status)
+	assert.Empty(t, body.Pending)
+	require.Len(t, body.Failing, 1)
+	assert.Equal(t, "missing", body.Failing[0].Name)
+	assert.Equal(t, missing, body.Failing[0].Path)
+	assert.Contains(t, body.Failing[0].Error, "no such file or directory")
+	assert.Nil(t, body.Failing[0].LastSuccess)
+}
+
+func TestReady_WithoutCollector_ReturnsOK(t *testing.T) {
+	t.Parallel()
+
+	baseURL := startServer(t)
+
+	status, body := getReadiness(t, baseURL)
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "ready", body.Status)
+}
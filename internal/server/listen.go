@@ -0,0 +1,91 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/coreos/go-systemd/v22/activation"
+	"go.uber.org/zap"
+)
+
+// unixSocketPrefix is the prefix of the listen addresses that are Unix domain socket paths
+const unixSocketPrefix = "unix:"
+
+// listen creates a listener for each of the listen addresses, or gets the sockets passed by systemd when
+// socket activation is enabled. The listeners already created are closed when one of them fails.
+func (s *MetricsServer) listen() ([]net.Listener, error) {
+	if s.systemdSocket {
+		files, err := activation.Listeners()
+		if err != nil {
+			return nil, fmt.Errorf("error getting systemd sockets: %w", err)
+		}
+
+		listeners := streamListeners(files)
+		if skipped := len(files) - len(listeners); skipped > 0 {
+			s.logger.Warn("ignoring systemd sockets that are not stream sockets", zap.Int("count", skipped))
+		}
+
+		if len(listeners) == 0 {
+			return nil, errors.New("no systemd sockets found, the exporter must be started by a systemd socket unit")
+		}
+
+		return listeners, nil
+	}
+
+	if len(s.listenAddresses) == 0 {
+		return nil, errors.New("no listen addresses")
+	}
+
+	listeners := make([]net.Listener, 0, len(s.listenAddresses))
+	for _, address := range s.listenAddresses {
+		listener, err := listen(address)
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+
+			return nil, err
+		}
+
+		listeners = append(listeners, listener)
+	}
+
+	return listeners, nil
+}
+
+// streamListeners returns the listeners of the systemd sockets, leaving out the nil entries that
+// activation.Listeners returns for the file descriptors that are not stream sockets
+func streamListeners(listeners []net.Listener) []net.Listener {
+	filtered := make([]net.Listener, 0, len(listeners))
+	for _, listener := range listeners {
+		if listener != nil {
+			filtered = append(filtered, listener)
+		}
+	}
+
+	return filtered
+}
+
+// listen listens on a TCP address, like ":8080" or "[::1]:8080", or on a Unix domain socket, like "unix:/run/exporter.sock".
+// A socket file left behind by a previous run is removed, as it would prevent listening on the same path.
+func listen(address string) (net.Listener, error) {
+	path, isUnix := strings.CutPrefix(address, unixSocketPrefix)
+	if !isUnix {
+		return net.Listen("tcp", address)
+	}
+
+	if path == "" {
+		return nil, fmt.Errorf("invalid listen address %q: missing socket path", address)
+	}
+
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("error removing stale socket %s: %w", path, err)
+		}
+	}
+
+	return net.Listen("unix", path)
+}
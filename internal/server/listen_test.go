@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListen_WithStaleSocket_RemovesIt(t *testing.T) {
+	t.Parallel()
+
+	dir, err := os.MkdirTemp("", "exporter")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "exporter.sock")
+
+	// A listener that is not closed cleanly leaves its socket file behind
+	stale, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	listener, err := listen(unixSocketPrefix + socket)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	assert.Equal(t, "unix", listener.Addr().Network())
+}
+
+func TestListen_WithExistingFile_ReturnsError(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "exporter.sock")
+	require.NoError(t, os.WriteFile(path, []byte("data"), 0o600))
+
+	_, err := listen(unixSocketPrefix + path)
+
+	assert.Error(t, err)
+	assert.FileExists(t, path)
+}
+
+func TestStreamListeners_SkipsNilListeners(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	assert.Equal(t, []net.Listener{listener}, streamListeners([]net.Listener{nil, listener, nil}))
+	assert.Empty(t, streamListeners([]net.Listener{nil}))
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/go-kit/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// kitLogger adapts a zap logger to the go-kit logger used by the exporter toolkit
+type kitLogger struct {
+	logger *zap.Logger
+}
+
+var _ log.Logger = kitLogger{}
+
+// Log implements log.Logger. The "msg" and "level" keys are used as the message and level of the entry,
+// and all the other key value pairs are added as fields.
+func (l kitLogger) Log(keyvals ...interface{}) error {
+	msg := ""
+	lvl := zapcore.InfoLevel
+	fields := make([]zap.Field, 0, len(keyvals)/2)
+
+	for i := 0; i < len(keyvals); i += 2 {
+		key := fmt.Sprint(keyvals[i])
+		var value interface{}
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+
+		switch key {
+		case "msg":
+			msg = fmt.Sprint(value)
+		case "level":
+			if parsed, err := zapcore.ParseLevel(fmt.Sprint(value)); err == nil {
+				lvl = parsed
+			}
+		default:
+			fields = append(fields, zap.Any(key, value))
+		}
+	}
+
+	l.logger.Log(lvl, msg, fields...)
+
+	return nil
+}
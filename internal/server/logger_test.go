@@ -0,0 +1,31 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestKitLogger_Log(t *testing.T) {
+	t.Parallel()
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := kitLogger{logger: zap.New(core)}
+
+	require.NoError(t, logger.Log("level", "error", "msg", "TLS handshake failed", "address", ":8080"))
+	require.NoError(t, logger.Log("msg", "Listening on"))
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, zapcore.ErrorLevel, entries[0].Level)
+	assert.Equal(t, "TLS handshake failed", entries[0].Message)
+	assert.Equal(t, map[string]interface{}{"address": ":8080"}, entries[0].ContextMap())
+
+	assert.Equal(t, zapcore.InfoLevel, entries[1].Level)
+	assert.Equal(t, "Listening on", entries[1].Message)
+}
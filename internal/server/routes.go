@@ -4,15 +4,53 @@ import (
 	"net/http"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
 )
 
-func initRoutes(metricsPath string) http.Handler {
+func initRoutes(s *MetricsServer) http.Handler {
 	mux := http.NewServeMux()
-	mux.Handle(metricsPath, promhttp.Handler())
+	mux.Handle(s.metricsPath, promhttp.Handler())
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("Prometheus Directory Size Exporter is up and running"))
 	})
 
+	mux.HandleFunc("GET /-/healthy", s.handleHealthy)
+	mux.HandleFunc("GET /-/ready", s.handleReady)
+
+	if s.reloadFunc != nil {
+		mux.HandleFunc("/-/reload", s.handleReload)
+	}
+
+	if s.collector != nil {
+		mux.HandleFunc("GET /api/v1/directories", s.handleDirectories)
+		// Names of glob pattern matches are paths, so they can contain slashes. The "{name}/top" route can't be
+		// told apart from a name in the mux, so handleDirectory checks for it.
+		mux.HandleFunc("GET /api/v1/directories/{name...}", s.handleDirectory)
+		mux.HandleFunc("POST /api/v1/directories/scan", s.handleScanAll)
+		// Like for "{name}/top", handleScan checks for the "/scan" suffix
+		mux.HandleFunc("POST /api/v1/directories/{name...}", s.handleScan)
+	}
+
 	return mux
 }
+
+// handleReload reloads the exporter configuration
+func (s *MetricsServer) handleReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed, use POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.logger.Info("reloading configuration", zap.String("trigger", "http"))
+
+	if err := s.reloadFunc(); err != nil {
+		s.logger.Error("failed to reload configuration", zap.Error(err))
+		http.Error(w, "failed to reload configuration: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("Configuration reloaded"))
+}
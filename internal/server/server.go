@@ -2,19 +2,26 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/prometheus/exporter-toolkit/web"
 	"go.uber.org/zap"
+
+	"github.com/brpaz/prom-dirsize-exporter/internal/collector"
+	"github.com/brpaz/prom-dirsize-exporter/internal/config"
 )
 
 const (
-	DefaultMetricsPort = 8080
-	DefaultMetricsPath = "/metrics"
+	DefaultMetricsPort = config.DefaultMetricsPort
+	DefaultMetricsPath = config.DefaultMetricsPath
 
 	// Define constants for signal types
 	sigInt  = syscall.SIGINT
@@ -22,10 +29,21 @@ const (
 )
 
 type MetricsServer struct {
-	logger      *zap.Logger
-	httpServer  *http.Server
-	port        int
-	metricsPath string
+	logger *zap.Logger
+	// listenAddresses holds the TCP addresses and Unix socket paths the server listens on
+	listenAddresses []string
+	// systemdSocket makes the server listen on the sockets passed by systemd instead of the listen addresses
+	systemdSocket bool
+	metricsPath   string
+	// webConfigFile is the path of the exporter toolkit web config file, that enables TLS and basic auth
+	webConfigFile string
+	reloadFunc    func() error
+	collector     *collector.DirectoryCollector
+	handler       http.Handler
+
+	// mutex protects httpServers, that holds a server for each listener, nil until the server is started
+	mutex       sync.Mutex
+	httpServers []*http.Server
 }
 
 // MetricsServerOption is a function that configures a MetricsServer
@@ -38,10 +56,20 @@ func WithLogger(logger *zap.Logger) MetricsServerOption {
 	}
 }
 
-// WithPort sets the port of the MetricsServer
-func WithPort(port int) MetricsServerOption {
+// WithListenAddresses sets the addresses the MetricsServer listens on. Each address is either a TCP address,
+// like ":8080", "127.0.0.1:8080" or "[::1]:8080", or the path of a Unix domain socket prefixed by "unix:",
+// like "unix:/run/prom-dirsize-exporter.sock".
+func WithListenAddresses(addresses ...string) MetricsServerOption {
+	return func(c *MetricsServer) {
+		c.listenAddresses = addresses
+	}
+}
+
+// WithSystemdSocket makes the MetricsServer listen on the sockets passed by systemd socket activation,
+// instead of the listen addresses
+func WithSystemdSocket(enabled bool) MetricsServerOption {
 	return func(c *MetricsServer) {
-		c.port = port
+		c.systemdSocket = enabled
 	}
 }
 
@@ -52,38 +80,83 @@ func WithPath(metricsPath string) MetricsServerOption {
 	}
 }
 
+// WithWebConfigFile sets the path of the web config file, in the Prometheus exporter toolkit format, used to
+// enable TLS, client certificate verification and basic auth. The file and the certificates it references are read
+// again on every connection, so they can be changed without a restart. When empty, plain HTTP is used.
+func WithWebConfigFile(path string) MetricsServerOption {
+	return func(c *MetricsServer) {
+		c.webConfigFile = path
+	}
+}
+
+// WithReloadFunc sets the function called to reload the exporter configuration.
+// When set, the configuration can be reloaded with a POST request to /-/reload.
+func WithReloadFunc(reload func() error) MetricsServerOption {
+	return func(c *MetricsServer) {
+		c.reloadFunc = reload
+	}
+}
+
+// WithCollector sets the directory collector whose data is exposed by the JSON API.
+// When not set, the API endpoints are not available.
+func WithCollector(c *collector.DirectoryCollector) MetricsServerOption {
+	return func(s *MetricsServer) {
+		s.collector = c
+	}
+}
+
 // NewMetricsServer creates a new MetricsServer with the provided options.
 // It uses golang http.Server to create a new server instance to expose the prometheus metrics.
 func NewMetricsServer(opts ...MetricsServerOption) *MetricsServer {
 	srv := &MetricsServer{
-		port:        DefaultMetricsPort,
-		metricsPath: DefaultMetricsPath,
-		logger:      zap.NewNop(),
+		listenAddresses: []string{fmt.Sprintf(":%d", DefaultMetricsPort)},
+		metricsPath:     DefaultMetricsPath,
+		logger:          zap.NewNop(),
 	}
 
 	for _, opt := range opts {
 		opt(srv)
 	}
 
-	srv.httpServer = &http.Server{
-		Addr:    fmt.Sprintf(":%d", srv.port),
-		Handler: initRoutes(srv.metricsPath),
-	}
+	srv.handler = initRoutes(srv)
 
 	return srv
 }
 
+// Start starts the server and blocks until it's stopped by a signal or fails.
+// The web config file, when set, is validated before the server starts listening.
 func (s *MetricsServer) Start() error {
+	if err := web.Validate(s.webConfigFile); err != nil {
+		return fmt.Errorf("invalid web config file: %w", err)
+	}
+
+	listeners, err := s.listen()
+	if err != nil {
+		s.logger.Error("Failed to start HTTP server", zap.Error(err))
+		return err
+	}
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, sigInt, sigTerm)
 
-	errSrvStart := make(chan error, 1)
-	go func() {
-		s.logger.Info("Starting server on port", zap.Int("port", s.port))
-		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			errSrvStart <- err
-		}
-	}()
+	flags := &web.FlagConfig{WebConfigFile: &s.webConfigFile}
+
+	// Each listener gets its own http.Server, as the exporter toolkit sets up TLS and basic auth on the server
+	s.mutex.Lock()
+	for range listeners {
+		s.httpServers = append(s.httpServers, &http.Server{Handler: s.handler})
+	}
+	s.mutex.Unlock()
+
+	errSrvStart := make(chan error, len(listeners))
+	for i, listener := range listeners {
+		go func(httpServer *http.Server, listener net.Listener) {
+			s.logger.Info("Starting server", zap.String("address", listener.Addr().String()))
+			if err := web.Serve(listener, httpServer, flags, kitLogger{logger: s.logger}); err != nil && err != http.ErrServerClosed {
+				errSrvStart <- err
+			}
+		}(s.httpServers[i], listener)
+	}
 
 	// Block until a signal is received or the server fails to start
 	select {
@@ -92,13 +165,17 @@ func (s *MetricsServer) Start() error {
 		return err
 	case err := <-errSrvStart:
 		s.logger.Error("Failed to start HTTP server", zap.Error(err))
+		_ = s.Stop()
 		return err
 	}
 }
 
 // Stop stops the MetricsServer gracefully
 func (s *MetricsServer) Stop() error {
-	if s.httpServer == nil {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.httpServers) == 0 {
 		return nil
 	}
 
@@ -107,5 +184,12 @@ func (s *MetricsServer) Stop() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	return s.httpServer.Shutdown(ctx)
+	var errs []error
+	for _, httpServer := range s.httpServers {
+		if err := httpServer.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
 }
@@ -1,23 +1,40 @@
 package server_test
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
+	"math/big"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/brpaz/prom-dirsize-exporter/internal/server"
 	"github.com/brpaz/prom-dirsize-exporter/internal/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestNewMetricsServer(t *testing.T) {
 	t.Parallel()
 
 	srv := server.NewMetricsServer(
-		server.WithPort(3000),
+		server.WithListenAddresses(":3000"),
 		server.WithPath("/metrics"),
 		server.WithLogger(zap.NewNop()),
 	)
@@ -36,7 +53,7 @@ func TestMetricsServerStart_ReturnsSuccess(t *testing.T) {
 	}
 
 	srv := server.NewMetricsServer(
-		server.WithPort(port),
+		server.WithListenAddresses(fmt.Sprintf(":%d", port)),
 		server.WithLogger(logger),
 	)
 
@@ -76,7 +93,7 @@ func TestMetricsServerStart_WithInvalidPort_ReturnsError(t *testing.T) {
 
 	srv := server.NewMetricsServer(
 		server.WithLogger(zap.NewNop()),
-		server.WithPort(999999),
+		server.WithListenAddresses(":999999"),
 	)
 
 	t.Cleanup(func() {
@@ -133,14 +150,14 @@ func TestMetricsServer_ServesMetricsEndpoint(t *testing.T) {
 			if metricsPath != "" {
 				srv = server.NewMetricsServer(
 					server.WithLogger(logger),
-					server.WithPort(port),
+					server.WithListenAddresses(fmt.Sprintf(":%d", port)),
 					server.WithPath(metricsPath),
 				)
 
 				metricsEndpoint = fmt.Sprintf("http://localhost:%d%s", port, metricsPath)
 			} else {
 				srv = server.NewMetricsServer(
-					server.WithPort(port),
+					server.WithListenAddresses(fmt.Sprintf(":%d", port)),
 					server.WithLogger(logger),
 				)
 				metricsEndpoint = fmt.Sprintf("http://localhost:%d/metrics", port)
@@ -173,3 +190,293 @@ func TestMetricsServer_ServesMetricsEndpoint(t *testing.T) {
 		})
 	}
 }
+
+func TestMetricsServer_ReloadEndpoint(t *testing.T) {
+	t.Parallel()
+
+	scenarios := []struct {
+		name           string
+		method         string
+		reloadErr      error
+		expectedStatus int
+		expectedCalls  int
+	}{
+		{
+			name:           "Reloads successfully",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusOK,
+			expectedCalls:  1,
+		},
+		{
+			name:           "Reload fails",
+			method:         http.MethodPost,
+			reloadErr:      errors.New("invalid configuration"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  1,
+		},
+		{
+			name:           "Wrong method",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedCalls:  0,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+
+			port, err := testutil.GetFreePort()
+			if err != nil {
+				t.Fatalf("Error getting free port: %s", err)
+			}
+
+			var calls atomic.Int32
+			srv := server.NewMetricsServer(
+				server.WithLogger(zap.NewNop()),
+				server.WithListenAddresses(fmt.Sprintf(":%d", port)),
+				server.WithReloadFunc(func() error {
+					calls.Add(1)
+					return scenario.reloadErr
+				}),
+			)
+
+			go func() {
+				_ = srv.Start()
+			}()
+
+			t.Cleanup(func() {
+				_ = srv.Stop()
+			})
+
+			// Wait for a short time to allow the server to start.
+			time.Sleep(100 * time.Millisecond)
+
+			req, err := http.NewRequest(scenario.method, fmt.Sprintf("http://localhost:%d/-/reload", port), nil)
+			require.NoError(t, err)
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, scenario.expectedStatus, resp.StatusCode)
+			assert.Equal(t, int32(scenario.expectedCalls), calls.Load())
+		})
+	}
+}
+
+// writeWebConfig writes the web config file to a temporary directory and returns its path
+func writeWebConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "web-config.yml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+// writeCertificate writes a self-signed certificate for localhost and its key to dir, and returns it parsed
+func writeCertificate(t *testing.T, dir string) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tls.crt"), certPEM, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tls.key"), keyPEM, 0o600))
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert
+}
+
+func TestMetricsServer_WithWebConfigFile_RequiresBasicAuth(t *testing.T) {
+	t.Parallel()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	baseURL := startServer(t, server.WithWebConfigFile(writeWebConfig(t, fmt.Sprintf("basic_auth_users:\n  prometheus: %s\n", hash))))
+	url := baseURL + "/metrics"
+
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	req.SetBasicAuth("prometheus", "secret")
+
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestMetricsServer_WithWebConfigFile_ServesTLS(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	cert := writeCertificate(t, dir)
+
+	baseURL := startServer(t, server.WithWebConfigFile(writeWebConfig(t, fmt.Sprintf(
+		"tls_server_config:\n  cert_file: %s\n  key_file: %s\n",
+		filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"),
+	))))
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}},
+	}
+
+	resp, err := client.Get(strings.Replace(baseURL, "http://", "https://", 1) + "/metrics")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(baseURL + "/metrics")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestMetricsServerStart_WithInvalidWebConfigFile_ReturnsError(t *testing.T) {
+	t.Parallel()
+
+	srv := server.NewMetricsServer(
+		server.WithLogger(zap.NewNop()),
+		server.WithWebConfigFile(writeWebConfig(t, "tls_server_config:\n  cert_file: /missing/tls.crt\n  key_file: /missing/tls.key\n")),
+	)
+
+	err := srv.Start()
+
+	assert.ErrorContains(t, err, "invalid web config file")
+}
+
+func TestMetricsServer_WithListenAddresses_ListensOnAll(t *testing.T) {
+	t.Parallel()
+
+	port, err := testutil.GetFreePort()
+	require.NoError(t, err)
+
+	// Unix socket paths are limited to about 100 characters, which t.TempDir might exceed
+	dir, err := os.MkdirTemp("", "exporter")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "exporter.sock")
+
+	srv := server.NewMetricsServer(
+		server.WithLogger(zap.NewNop()),
+		server.WithListenAddresses(fmt.Sprintf("127.0.0.1:%d", port), "unix:"+socket),
+	)
+
+	go func() {
+		_ = srv.Start()
+	}()
+
+	t.Cleanup(func() {
+		_ = srv.Stop()
+	})
+
+	// Wait for a short time to allow the server to start.
+	time.Sleep(100 * time.Millisecond)
+
+	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/metrics", port))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	unixClient := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+
+	resp, err = unixClient.Get("http://localhost/metrics")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestMetricsServer_WithIPv6ListenAddress(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 is not available: %s", err)
+	}
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	srv := server.NewMetricsServer(
+		server.WithLogger(zap.NewNop()),
+		server.WithListenAddresses(address),
+	)
+
+	go func() {
+		_ = srv.Start()
+	}()
+
+	t.Cleanup(func() {
+		_ = srv.Stop()
+	})
+
+	// Wait for a short time to allow the server to start.
+	time.Sleep(100 * time.Millisecond)
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/metrics", address))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestMetricsServerStart_WithSystemdSocket_WithoutSockets_ReturnsError(t *testing.T) {
+	t.Setenv("LISTEN_PID", "")
+	t.Setenv("LISTEN_FDS", "")
+
+	srv := server.NewMetricsServer(
+		server.WithLogger(zap.NewNop()),
+		server.WithSystemdSocket(true),
+	)
+
+	err := srv.Start()
+
+	assert.ErrorContains(t, err, "no systemd sockets found")
+}
+
+func TestMetricsServerStart_WithEmptySocketPath_ReturnsError(t *testing.T) {
+	t.Parallel()
+
+	srv := server.NewMetricsServer(
+		server.WithLogger(zap.NewNop()),
+		server.WithListenAddresses("unix:"),
+	)
+
+	err := srv.Start()
+
+	assert.ErrorContains(t, err, "missing socket path")
+}
@@ -0,0 +1,152 @@
+package walker
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// pattern is a gitignore style pattern
+type pattern struct {
+	// negate is set for patterns starting with "!", that re-include entries excluded by previous patterns
+	negate bool
+	// dirOnly is set for patterns ending with "/", that only match directories
+	dirOnly bool
+	// segments holds the path segments of the pattern. Patterns without a slash match at any depth,
+	// so they start with a "**" segment.
+	segments []string
+}
+
+// ValidatePattern checks if a gitignore style pattern is valid
+func ValidatePattern(p string) error {
+	_, err := parsePattern(p)
+	return err
+}
+
+// parsePattern parses a gitignore style pattern
+func parsePattern(p string) (pattern, error) {
+	parsed := pattern{}
+
+	if strings.HasPrefix(p, "!") {
+		parsed.negate = true
+		p = p[1:]
+	}
+
+	if strings.HasSuffix(p, "/") {
+		parsed.dirOnly = true
+		p = strings.TrimRight(p, "/")
+	}
+
+	if p == "" {
+		return pattern{}, fmt.Errorf("empty pattern")
+	}
+
+	// A pattern with a slash, other than a trailing one, is relative to the root
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+
+	parsed.segments = strings.Split(p, "/")
+	if !anchored {
+		parsed.segments = append([]string{"**"}, parsed.segments...)
+	}
+
+	for _, segment := range parsed.segments {
+		if segment == "**" {
+			continue
+		}
+
+		if _, err := filepath.Match(segment, ""); err != nil {
+			return pattern{}, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+	}
+
+	return parsed, nil
+}
+
+// matches returns true if the path, relative to the root and using forward slashes, matches the pattern
+func (p pattern) matches(relPath string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+
+	return matchSegments(p.segments, strings.Split(relPath, "/"))
+}
+
+// matchSegments matches the path segments against the pattern segments, with "**" matching any number of segments
+func matchSegments(patternSegments []string, pathSegments []string) bool {
+	if len(patternSegments) == 0 {
+		return len(pathSegments) == 0
+	}
+
+	if patternSegments[0] == "**" {
+		for i := 0; i <= len(pathSegments); i++ {
+			if matchSegments(patternSegments[1:], pathSegments[i:]) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if len(pathSegments) == 0 {
+		return false
+	}
+
+	if matched, _ := filepath.Match(patternSegments[0], pathSegments[0]); !matched {
+		return false
+	}
+
+	return matchSegments(patternSegments[1:], pathSegments[1:])
+}
+
+// matcher matches paths against a list of gitignore style patterns, where the last matching pattern wins
+type matcher struct {
+	patterns []pattern
+}
+
+// newMatcher creates a matcher for the patterns. Invalid patterns are ignored, as they should be validated
+// with ValidatePattern beforehand.
+func newMatcher(patterns []string) *matcher {
+	m := &matcher{}
+
+	for _, p := range patterns {
+		if parsed, err := parsePattern(p); err == nil {
+			m.patterns = append(m.patterns, parsed)
+		}
+	}
+
+	return m
+}
+
+// empty returns true if the matcher has no patterns
+func (m *matcher) empty() bool {
+	return len(m.patterns) == 0
+}
+
+// match returns true if the path is matched by the patterns, taking negated patterns into account
+func (m *matcher) match(relPath string, isDir bool) bool {
+	matched := false
+
+	for _, p := range m.patterns {
+		if p.matches(relPath, isDir) {
+			matched = !p.negate
+		}
+	}
+
+	return matched
+}
+
+// matchSelfOrParent returns true if the path or any of its parent directories is matched by the patterns
+func (m *matcher) matchSelfOrParent(relPath string, isDir bool) bool {
+	if m.match(relPath, isDir) {
+		return true
+	}
+
+	for i := strings.LastIndex(relPath, "/"); i > 0; i = strings.LastIndex(relPath[:i], "/") {
+		if m.match(relPath[:i], true) {
+			return true
+		}
+	}
+
+	return false
+}
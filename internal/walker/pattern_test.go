@@ -0,0 +1,61 @@
+package walker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatcher_Match(t *testing.T) {
+	t.Parallel()
+
+	scenarios := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{name: "Name at root", patterns: []string{"*.tmp"}, path: "a.tmp", expected: true},
+		{name: "Name at any depth", patterns: []string{"*.tmp"}, path: "a/b/c.tmp", expected: true},
+		{name: "Name does not match", patterns: []string{"*.tmp"}, path: "a/b/c.txt", expected: false},
+		{name: "Directory name", patterns: []string{"node_modules"}, path: "app/node_modules", isDir: true, expected: true},
+		{name: "Directory only pattern with file", patterns: []string{".snapshot/"}, path: "a/.snapshot", expected: false},
+		{name: "Directory only pattern with directory", patterns: []string{".snapshot/"}, path: "a/.snapshot", isDir: true, expected: true},
+		{name: "Anchored pattern at root", patterns: []string{"/cache"}, path: "cache", isDir: true, expected: true},
+		{name: "Anchored pattern in subdirectory", patterns: []string{"/cache"}, path: "a/cache", isDir: true, expected: false},
+		{name: "Pattern with slash is anchored", patterns: []string{"a/logs"}, path: "b/a/logs", isDir: true, expected: false},
+		{name: "Double star in the middle", patterns: []string{"a/**/b"}, path: "a/x/y/b", expected: true},
+		{name: "Double star matches no directory", patterns: []string{"a/**/b"}, path: "a/b", expected: true},
+		{name: "Trailing double star", patterns: []string{"vm/**"}, path: "vm/disk.img", expected: true},
+		{name: "Negated pattern", patterns: []string{"*.log", "!important.log"}, path: "important.log", expected: false},
+		{name: "Last pattern wins", patterns: []string{"!important.log", "*.log"}, path: "important.log", expected: true},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := newMatcher(scenario.patterns)
+
+			assert.Equal(t, scenario.expected, m.match(scenario.path, scenario.isDir))
+		})
+	}
+}
+
+func TestMatcher_MatchSelfOrParent(t *testing.T) {
+	t.Parallel()
+
+	m := newMatcher([]string{"videos/"})
+
+	assert.True(t, m.matchSelfOrParent("media/videos/movie.mp4", false))
+	assert.False(t, m.matchSelfOrParent("media/music/song.mp3", false))
+}
+
+func TestValidatePattern(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, ValidatePattern("!**/*.tmp"))
+	assert.Error(t, ValidatePattern("[a-"))
+	assert.Error(t, ValidatePattern("/"))
+}
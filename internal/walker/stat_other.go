@@ -0,0 +1,35 @@
+//go:build !unix
+
+package walker
+
+import "io/fs"
+
+// fileID uniquely identifies a file in the system
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// deviceID returns the ID of the device where the file is stored.
+// It's not available on this platform, so all files are considered to be on the same device.
+func deviceID(_ fs.FileInfo) uint64 {
+	return 0
+}
+
+// hardLinkID returns the ID of a file that has more than one hard link.
+// It's not available on this platform, so hard links are counted as regular files.
+func hardLinkID(_ fs.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
+
+// allocatedSize returns the space allocated on disk for the file.
+// It's not available on this platform, so the apparent size is used instead.
+func allocatedSize(info fs.FileInfo) int64 {
+	return info.Size()
+}
+
+// ownerIDs returns the IDs of the user and group that own the file.
+// It's not available on this platform, so the owner is never known.
+func ownerIDs(_ fs.FileInfo) (uid uint32, gid uint32, ok bool) {
+	return 0, 0, false
+}
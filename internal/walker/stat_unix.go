@@ -0,0 +1,56 @@
+//go:build unix
+
+package walker
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileID uniquely identifies a file in the system
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// deviceID returns the ID of the device where the file is stored
+func deviceID(info fs.FileInfo) uint64 {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0
+	}
+
+	return uint64(stat.Dev) //nolint:unconvert // Dev type differs between platforms
+}
+
+// hardLinkID returns the ID of a file that has more than one hard link.
+// Directories are never reported, as their link count includes their subdirectories.
+func hardLinkID(info fs.FileInfo) (fileID, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || info.IsDir() || stat.Nlink <= 1 {
+		return fileID{}, false
+	}
+
+	return fileID{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}, true //nolint:unconvert // types differ between platforms
+}
+
+// allocatedSize returns the space allocated on disk for the file, which is reported in 512 bytes blocks
+// regardless of the filesystem block size
+func allocatedSize(info fs.FileInfo) int64 {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.Size()
+	}
+
+	return int64(stat.Blocks) * 512 //nolint:unconvert // Blocks type differs between platforms
+}
+
+// ownerIDs returns the IDs of the user and group that own the file
+func ownerIDs(info fs.FileInfo) (uid uint32, gid uint32, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+
+	return stat.Uid, stat.Gid, true
+}
@@ -0,0 +1,137 @@
+package walker
+
+import (
+	"container/heap"
+	"sort"
+	"strings"
+)
+
+// Entry is a file or directory found during the walk
+type Entry struct {
+	// Path is the path of the entry relative to the root, using forward slashes
+	Path string
+	// Size is the size of the entry in bytes. For directories, it includes everything below them.
+	Size int64
+}
+
+// topEntries keeps the n largest entries it's offered, using a min-heap so the smallest one can be
+// replaced cheaply.
+type topEntries struct {
+	n       int
+	entries entryHeap
+}
+
+// newTopEntries creates a new topEntries that keeps up to n entries
+func newTopEntries(n int) *topEntries {
+	return &topEntries{n: n}
+}
+
+// offer adds the entry if it's one of the n largest seen so far
+func (t *topEntries) offer(entry Entry) {
+	if t.n <= 0 {
+		return
+	}
+
+	if len(t.entries) < t.n {
+		heap.Push(&t.entries, entry)
+		return
+	}
+
+	if entry.Size > t.entries[0].Size {
+		t.entries[0] = entry
+		heap.Fix(&t.entries, 0)
+	}
+}
+
+// sorted returns the entries sorted from the largest to the smallest
+func (t *topEntries) sorted() []Entry {
+	entries := make([]Entry, len(t.entries))
+	copy(entries, t.entries)
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Size != entries[j].Size {
+			return entries[i].Size > entries[j].Size
+		}
+
+		return entries[i].Path < entries[j].Path
+	})
+
+	return entries
+}
+
+// entryHeap implements heap.Interface as a min-heap of entries by size
+type entryHeap []Entry
+
+func (h entryHeap) Len() int           { return len(h) }
+func (h entryHeap) Less(i, j int) bool { return h[i].Size < h[j].Size }
+func (h entryHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *entryHeap) Push(x any) {
+	*h = append(*h, x.(Entry))
+}
+
+func (h *entryHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return entry
+}
+
+// dirFrame holds the accumulated size of a directory that is still being walked
+type dirFrame struct {
+	path string
+	size int64
+}
+
+// dirStack tracks the directories being walked, to calculate the total size of each directory without
+// keeping all of them in memory. As the walk is depth first, a directory is complete as soon as an entry
+// outside of it is visited.
+type dirStack struct {
+	frames []dirFrame
+	top    *topEntries
+}
+
+// visit closes the directories that don't contain the entry and adds the entry to the directory that contains it.
+// Directories are pushed to the stack, so their contents can be added to them.
+func (s *dirStack) visit(relPath string, isDir bool, size int64) {
+	for len(s.frames) > 0 && !isInside(relPath, s.frames[len(s.frames)-1].path) {
+		s.pop()
+	}
+
+	if isDir {
+		s.frames = append(s.frames, dirFrame{path: relPath, size: size})
+		return
+	}
+
+	if len(s.frames) > 0 {
+		s.frames[len(s.frames)-1].size += size
+	}
+}
+
+// close closes all the remaining directories
+func (s *dirStack) close() {
+	for len(s.frames) > 0 {
+		s.pop()
+	}
+}
+
+// pop closes the innermost directory, adding its size to its parent and offering it to the top entries.
+// The root, with an empty path, is never offered.
+func (s *dirStack) pop() {
+	frame := s.frames[len(s.frames)-1]
+	s.frames = s.frames[:len(s.frames)-1]
+
+	if len(s.frames) > 0 {
+		s.frames[len(s.frames)-1].size += frame.size
+	}
+
+	if frame.path != "" {
+		s.top.offer(Entry{Path: frame.path, Size: frame.size})
+	}
+}
+
+// isInside returns true if the relative path is inside the directory, with the root being an empty path
+func isInside(relPath string, dir string) bool {
+	return dir == "" || strings.HasPrefix(relPath, dir+"/")
+}
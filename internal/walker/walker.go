@@ -0,0 +1,471 @@
+// Package walker provides a native directory tree walker used to calculate directory sizes
+// without depending on external tools like "du".
+package walker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// OtherFileType is the file type of the files whose extension is not mapped to any type
+const OtherFileType = "other"
+
+// Result holds the outcome of walking a directory tree.
+type Result struct {
+	// Size is the apparent size, in bytes, of all the entries found under the root, including the root itself.
+	Size int64
+	// DiskUsage is the space, in bytes, allocated on disk for all the entries. It differs from Size for
+	// sparse files and filesystems with compression or deduplication.
+	DiskUsage int64
+	// Files is the number of regular files found. Hard links to the same file are only counted once.
+	Files int64
+	// Directories is the number of subdirectories found, not including the root
+	Directories int64
+	// Symlinks is the number of symbolic links found
+	Symlinks int64
+	// ExcludedSize is the apparent size, in bytes, of the entries left out by the include and exclude patterns
+	ExcludedSize int64
+	// Subdirectories holds the size of each subdirectory up to the configured depth, indexed by its path
+	// relative to the root, using forward slashes. Each size includes everything below the subdirectory.
+	Subdirectories map[string]int64
+	// SizeByType holds the size of the regular files of each file type, when file types are set. Files whose
+	// extension has no type are added to OtherFileType.
+	SizeByType map[string]int64
+	// SizeByUser and SizeByGroup hold the size of the entries owned by each user and group ID, when the
+	// owners are tracked. They are empty on platforms where the owner is not available.
+	SizeByUser  map[uint32]int64
+	SizeByGroup map[uint32]int64
+	// OldestFile and NewestFile hold the oldest and newest modification times of the regular files found.
+	// They are zero when no files are found.
+	OldestFile time.Time
+	NewestFile time.Time
+	// SizeByAge holds the size of the regular files modified within each of the age buckets, when set. As in a
+	// Prometheus histogram, the sizes are cumulative, and there is an extra last element with all the files.
+	SizeByAge []int64
+	// LargestFiles holds the largest files found, sorted from the largest to the smallest
+	LargestFiles []Entry
+	// LargestDirectories holds the largest subdirectories found, sorted from the largest to the smallest
+	LargestDirectories []Entry
+	// Errors holds the non fatal errors found during the walk, like entries that could not be read.
+	// Entries deleted during the walk are not errors. When not empty, Size only reflects the entries that
+	// were accessible.
+	Errors []error
+}
+
+// Partial returns true if some entries could not be read during the walk.
+func (r *Result) Partial() bool {
+	return len(r.Errors) > 0
+}
+
+// Walker calculates directory sizes by walking the directory tree.
+type Walker struct {
+	oneFileSystem bool
+	excludes      *matcher
+	includes      *matcher
+	depth         int
+	topEntries    int
+	fileTypes     map[string]string
+	ageBuckets    []time.Duration
+	owners        bool
+}
+
+// Option represents an option to customize Walker behavior
+type Option func(*Walker)
+
+// WithOneFileSystem makes the walker skip directories that are on a different filesystem than the root.
+func WithOneFileSystem(enabled bool) Option {
+	return func(w *Walker) {
+		w.oneFileSystem = enabled
+	}
+}
+
+// WithExcludes sets gitignore style patterns of entries to leave out of the walk. Everything below an excluded
+// directory is left out too. Excluded entries are still walked, so their size can be reported in the result.
+func WithExcludes(patterns []string) Option {
+	return func(w *Walker) {
+		w.excludes = newMatcher(patterns)
+	}
+}
+
+// WithIncludes sets gitignore style patterns of the entries to walk. When set, only the entries matching them,
+// or inside a directory matching them, are counted. Exclude patterns take precedence.
+func WithIncludes(patterns []string) Option {
+	return func(w *Walker) {
+		w.includes = newMatcher(patterns)
+	}
+}
+
+// WithDepth makes the walker also report the size of each subdirectory up to the given depth.
+// A depth of 1 reports the direct children of the root, 2 also their children and so on. 0 disables it.
+func WithDepth(depth int) Option {
+	return func(w *Walker) {
+		w.depth = depth
+	}
+}
+
+// WithTopEntries makes the walker keep the n largest files and the n largest subdirectories. 0 disables it.
+func WithTopEntries(n int) Option {
+	return func(w *Walker) {
+		w.topEntries = n
+	}
+}
+
+// WithFileTypes makes the walker report the size of the regular files by type. The types are indexed by the
+// file extension, in lower case and including the leading dot, like ".mp4". Extensions with multiple dots,
+// like ".tar.gz", take precedence over the last one. Nil disables it.
+func WithFileTypes(types map[string]string) Option {
+	return func(w *Walker) {
+		w.fileTypes = types
+	}
+}
+
+// WithAgeBuckets makes the walker report the size of the regular files by the age of their modification time.
+// The buckets must be sorted in ascending order. Nil disables it.
+func WithAgeBuckets(buckets []time.Duration) Option {
+	return func(w *Walker) {
+		w.ageBuckets = buckets
+	}
+}
+
+// WithOwners makes the walker report the size of the entries by the user and group that own them
+func WithOwners(enabled bool) Option {
+	return func(w *Walker) {
+		w.owners = enabled
+	}
+}
+
+// New creates a new Walker with the provided options
+func New(opts ...Option) *Walker {
+	w := &Walker{
+		excludes: newMatcher(nil),
+		includes: newMatcher(nil),
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w
+}
+
+// Walk walks the directory tree starting at root and returns the accumulated result.
+// Like "du -sb", hard links are only counted once and symbolic links are not followed, except for the root itself.
+// An error is only returned when the root cannot be read or the context is cancelled. Errors in individual entries
+// are recorded in the result instead.
+func (w *Walker) Walk(ctx context.Context, root string) (*Result, error) {
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving directory %s: %w", root, err)
+	}
+
+	rootInfo, err := os.Stat(resolvedRoot)
+	if err != nil {
+		return nil, fmt.Errorf("error reading directory %s: %w", root, err)
+	}
+
+	if !rootInfo.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	// Make sure the root itself can be listed, so a permission error is reported as a failure instead of
+	// a partial result with only the size of the root entry.
+	f, err := os.Open(resolvedRoot)
+	if err != nil {
+		return nil, fmt.Errorf("error opening directory %s: %w", root, err)
+	}
+	_ = f.Close()
+
+	s := &walkState{
+		walker:       w,
+		root:         resolvedRoot,
+		now:          time.Now(),
+		result:       &Result{Subdirectories: make(map[string]int64)},
+		rootDev:      deviceID(rootInfo),
+		seen:         make(map[fileID]struct{}),
+		excludedSeen: make(map[fileID]struct{}),
+	}
+
+	if w.owners {
+		s.result.SizeByUser = make(map[uint32]int64)
+		s.result.SizeByGroup = make(map[uint32]int64)
+	}
+
+	if len(w.ageBuckets) > 0 {
+		s.result.SizeByAge = make([]int64, len(w.ageBuckets)+1)
+	}
+
+	if w.fileTypes != nil {
+		s.result.SizeByType = map[string]int64{OtherFileType: 0}
+		for _, fileType := range w.fileTypes {
+			s.result.SizeByType[fileType] = 0
+		}
+	}
+
+	if w.topEntries > 0 {
+		s.largestFiles = newTopEntries(w.topEntries)
+		s.dirs = &dirStack{top: newTopEntries(w.topEntries)}
+	}
+
+	err = filepath.WalkDir(resolvedRoot, func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		return s.visit(path, d, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.dirs != nil {
+		s.dirs.close()
+		s.result.LargestFiles = s.largestFiles.sorted()
+		s.result.LargestDirectories = s.dirs.top.sorted()
+	}
+
+	return s.result, nil
+}
+
+// walkState holds the state of a single walk
+type walkState struct {
+	walker  *Walker
+	root    string
+	now     time.Time
+	result  *Result
+	rootDev uint64
+	// seen and excludedSeen hold the hard linked files already counted and excluded
+	seen         map[fileID]struct{}
+	excludedSeen map[fileID]struct{}
+
+	// excludedDir is the relative path, with a trailing slash, of the excluded directory being walked, if any
+	excludedDir string
+
+	// largestFiles and dirs are only set when the largest entries are tracked
+	largestFiles *topEntries
+	dirs         *dirStack
+}
+
+// visit processes a single entry found during the walk
+func (s *walkState) visit(path string, d fs.DirEntry, err error) error {
+	// Entries deleted while the directory is walked, like temporary files, are not errors
+	if path != s.root && errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		// Errors of excluded entries don't affect the reported size
+		if path == s.root || !s.excluded(s.relPath(path), d != nil && d.IsDir()) {
+			s.result.Errors = append(s.result.Errors, err)
+		}
+		if d != nil && d.IsDir() {
+			return fs.SkipDir
+		}
+
+		return nil
+	}
+
+	info, err := d.Info()
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			s.result.Errors = append(s.result.Errors, err)
+		}
+		return nil
+	}
+
+	if d.IsDir() && s.walker.oneFileSystem && deviceID(info) != s.rootDev {
+		return fs.SkipDir
+	}
+
+	// Exclusion is checked first, so a hard link that is counted isn't skipped because another link to the
+	// same file was excluded
+	if path != s.root && s.excluded(s.relPath(path), d.IsDir()) {
+		if s.firstLink(s.excludedSeen, info) {
+			s.result.ExcludedSize += info.Size()
+		}
+		return nil
+	}
+
+	if !s.firstLink(s.seen, info) {
+		return nil
+	}
+
+	s.result.Size += info.Size()
+	s.result.DiskUsage += allocatedSize(info)
+	s.count(path, d)
+	s.addToFileTypes(d, info.Size())
+	s.addToFileAges(info)
+	s.addToOwners(info)
+	s.addToSubdirectories(path, d.IsDir(), info.Size())
+	s.addToTopEntries(path, d.IsDir(), info.Size())
+
+	return nil
+}
+
+// firstLink returns true if the entry is not a hard link to a file already in seen, and adds it to seen
+func (s *walkState) firstLink(seen map[fileID]struct{}, info fs.FileInfo) bool {
+	id, ok := hardLinkID(info)
+	if !ok {
+		return true
+	}
+
+	if _, found := seen[id]; found {
+		return false
+	}
+	seen[id] = struct{}{}
+
+	return true
+}
+
+// count increments the counter of the entry type
+func (s *walkState) count(path string, d fs.DirEntry) {
+	switch {
+	case d.IsDir():
+		if path != s.root {
+			s.result.Directories++
+		}
+	case d.Type()&fs.ModeSymlink != 0:
+		s.result.Symlinks++
+	case d.Type().IsRegular():
+		s.result.Files++
+	}
+}
+
+// addToFileTypes adds the size of the entry to its file type, if it's a regular file
+func (s *walkState) addToFileTypes(d fs.DirEntry, size int64) {
+	if s.result.SizeByType == nil || !d.Type().IsRegular() {
+		return
+	}
+
+	s.result.SizeByType[fileType(s.walker.fileTypes, d.Name())] += size
+}
+
+// addToOwners adds the size of the entry to the user and group that own it
+func (s *walkState) addToOwners(info fs.FileInfo) {
+	if s.result.SizeByUser == nil {
+		return
+	}
+
+	if uid, gid, ok := ownerIDs(info); ok {
+		s.result.SizeByUser[uid] += info.Size()
+		s.result.SizeByGroup[gid] += info.Size()
+	}
+}
+
+// addToFileAges tracks the modification time of the entry, if it's a regular file
+func (s *walkState) addToFileAges(info fs.FileInfo) {
+	if !info.Mode().IsRegular() {
+		return
+	}
+
+	modTime := info.ModTime()
+	if s.result.OldestFile.IsZero() || modTime.Before(s.result.OldestFile) {
+		s.result.OldestFile = modTime
+	}
+	if modTime.After(s.result.NewestFile) {
+		s.result.NewestFile = modTime
+	}
+
+	if s.result.SizeByAge == nil {
+		return
+	}
+
+	// Files with a modification time in the future are counted in all the buckets
+	age := s.now.Sub(modTime)
+	for i, bucket := range s.walker.ageBuckets {
+		if age <= bucket {
+			s.result.SizeByAge[i] += info.Size()
+		}
+	}
+	s.result.SizeByAge[len(s.walker.ageBuckets)] += info.Size()
+}
+
+// addToTopEntries tracks the entry to find the largest files and directories
+func (s *walkState) addToTopEntries(path string, isDir bool, size int64) {
+	if s.dirs == nil {
+		return
+	}
+
+	relPath := s.relPath(path)
+	s.dirs.visit(relPath, isDir, size)
+
+	if !isDir {
+		s.largestFiles.offer(Entry{Path: relPath, Size: size})
+	}
+}
+
+// relPath returns the path relative to the root using forward slashes, with the root itself being an empty path
+func (s *walkState) relPath(path string) string {
+	if path == s.root {
+		return ""
+	}
+
+	relPath, err := filepath.Rel(s.root, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+
+	return filepath.ToSlash(relPath)
+}
+
+// addToSubdirectories adds the size of the entry to each of its parent subdirectories, and to itself if it's a
+// directory, as long as they are within the configured depth
+func (s *walkState) addToSubdirectories(path string, isDir bool, size int64) {
+	if s.walker.depth <= 0 || path == s.root {
+		return
+	}
+
+	segments := strings.Split(s.relPath(path), "/")
+	for i := 1; i <= len(segments) && i <= s.walker.depth; i++ {
+		if i == len(segments) && !isDir {
+			break
+		}
+
+		s.result.Subdirectories[strings.Join(segments[:i], "/")] += size
+	}
+}
+
+// fileType returns the type of the file with the given name, trying its longest extension first.
+// Files without a known extension are of OtherFileType.
+func fileType(types map[string]string, name string) string {
+	name = strings.ToLower(name)
+
+	// The first character is skipped, so hidden files like ".bashrc" are not taken as an extension
+	for i := 1; i < len(name); i++ {
+		if name[i] != '.' {
+			continue
+		}
+
+		if fileType, ok := types[name[i:]]; ok {
+			return fileType
+		}
+	}
+
+	return OtherFileType
+}
+
+// excluded returns true if the entry is left out by the include and exclude patterns. Entries inside an
+// excluded directory are excluded without matching them again, as the walk is depth first.
+func (s *walkState) excluded(relPath string, isDir bool) bool {
+	if s.excludedDir != "" {
+		if strings.HasPrefix(relPath, s.excludedDir) {
+			return true
+		}
+		s.excludedDir = ""
+	}
+
+	if s.walker.excludes.match(relPath, isDir) {
+		if isDir {
+			s.excludedDir = relPath + "/"
+		}
+
+		return true
+	}
+
+	return !s.walker.includes.empty() && !s.walker.includes.matchSelfOrParent(relPath, isDir)
+}
@@ -0,0 +1,28 @@
+package walker
+
+import (
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWalkState_Visit_IgnoresEntriesDeletedDuringTheWalk(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	s := &walkState{walker: New(), root: root, result: &Result{}}
+
+	gone := filepath.Join(root, "session.tmp")
+	err := s.visit(gone, nil, &fs.PathError{Op: "lstat", Path: gone, Err: fs.ErrNotExist})
+
+	assert.NoError(t, err)
+	assert.Empty(t, s.result.Errors)
+
+	denied := filepath.Join(root, "private")
+	err = s.visit(denied, nil, &fs.PathError{Op: "open", Path: denied, Err: fs.ErrPermission})
+
+	assert.NoError(t, err)
+	assert.Len(t, s.result.Errors, 1)
+}
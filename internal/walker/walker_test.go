@@ -0,0 +1,438 @@
+package walker_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/prom-dirsize-exporter/internal/walker"
+)
+
+// createFile creates a file with the given size in bytes
+func createFile(t *testing.T, path string, size int) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, make([]byte, size), 0o600))
+}
+
+// entrySize returns the apparent size of an entry itself, without following it
+func entrySize(t *testing.T, path string) int64 {
+	t.Helper()
+
+	info, err := os.Lstat(path)
+	require.NoError(t, err)
+
+	return info.Size()
+}
+
+func TestWalk_SumsFileSizes(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	createFile(t, filepath.Join(root, "a.txt"), 100)
+	createFile(t, filepath.Join(root, "sub", "b.txt"), 50)
+
+	result, err := walker.New().Walk(context.Background(), root)
+	require.NoError(t, err)
+
+	expected := 150 + entrySize(t, root) + entrySize(t, filepath.Join(root, "sub"))
+	assert.Equal(t, expected, result.Size)
+	assert.False(t, result.Partial())
+}
+
+func TestWalk_CountsEntriesByType(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	createFile(t, filepath.Join(root, "a.txt"), 10)
+	createFile(t, filepath.Join(root, "sub", "b.txt"), 10)
+	createFile(t, filepath.Join(root, "sub", "nested", "c.txt"), 10)
+	require.NoError(t, os.Symlink(filepath.Join(root, "a.txt"), filepath.Join(root, "link")))
+
+	result, err := walker.New().Walk(context.Background(), root)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(3), result.Files)
+	assert.Equal(t, int64(2), result.Directories)
+	assert.Equal(t, int64(1), result.Symlinks)
+}
+
+func TestWalk_CountsHardLinksOnce(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("hard link detection is not supported on windows")
+	}
+
+	root := t.TempDir()
+	createFile(t, filepath.Join(root, "original.txt"), 100)
+	require.NoError(t, os.Link(filepath.Join(root, "original.txt"), filepath.Join(root, "link.txt")))
+
+	result, err := walker.New().Walk(context.Background(), root)
+	require.NoError(t, err)
+
+	assert.Equal(t, 100+entrySize(t, root), result.Size)
+	assert.Equal(t, int64(1), result.Files)
+}
+
+func TestWalk_DoesNotFollowSymlinks(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	target := t.TempDir()
+	createFile(t, filepath.Join(target, "big.txt"), 1000)
+	require.NoError(t, os.Symlink(target, filepath.Join(root, "link")))
+
+	result, err := walker.New().Walk(context.Background(), root)
+	require.NoError(t, err)
+
+	assert.Equal(t, entrySize(t, root)+entrySize(t, filepath.Join(root, "link")), result.Size)
+}
+
+func TestWalk_WithNonExistingDirectory_ReturnsError(t *testing.T) {
+	t.Parallel()
+
+	_, err := walker.New().Walk(context.Background(), filepath.Join(t.TempDir(), "missing"))
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestWalk_WithFile_ReturnsError(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "file.txt")
+	createFile(t, file, 10)
+
+	_, err := walker.New().Walk(context.Background(), file)
+
+	assert.ErrorContains(t, err, "is not a directory")
+}
+
+func TestWalk_WithUnreadableSubdirectory_ReturnsPartialResult(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("permissions can't be enforced in this environment")
+	}
+
+	root := t.TempDir()
+	createFile(t, filepath.Join(root, "a.txt"), 100)
+	createFile(t, filepath.Join(root, "locked", "b.txt"), 50)
+	require.NoError(t, os.Chmod(filepath.Join(root, "locked"), 0o000))
+	t.Cleanup(func() {
+		_ = os.Chmod(filepath.Join(root, "locked"), 0o755)
+	})
+
+	result, err := walker.New().Walk(context.Background(), root)
+	require.NoError(t, err)
+
+	assert.True(t, result.Partial())
+	assert.GreaterOrEqual(t, result.Size, int64(100))
+}
+
+func TestWalk_WithCancelledContext_ReturnsError(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := walker.New().Walk(ctx, t.TempDir())
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestWalk_WithExcludes_SkipsMatchingEntries(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	createFile(t, filepath.Join(root, "a.txt"), 100)
+	createFile(t, filepath.Join(root, "b.tmp"), 200)
+	createFile(t, filepath.Join(root, "cache", "c.txt"), 300)
+	createFile(t, filepath.Join(root, "sub", "d.tmp"), 400)
+
+	result, err := walker.New(walker.WithExcludes([]string{"*.tmp", "cache"})).Walk(context.Background(), root)
+	require.NoError(t, err)
+
+	assert.Equal(t, 100+entrySize(t, root)+entrySize(t, filepath.Join(root, "sub")), result.Size)
+}
+
+func TestWalk_WithExcludes_MatchesRelativePaths(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	createFile(t, filepath.Join(root, "a", "logs", "a.log"), 100)
+	createFile(t, filepath.Join(root, "b", "logs", "b.log"), 200)
+
+	result, err := walker.New(walker.WithExcludes([]string{"a/logs"})).Walk(context.Background(), root)
+	require.NoError(t, err)
+
+	expected := 200 + entrySize(t, root) +
+		entrySize(t, filepath.Join(root, "a")) +
+		entrySize(t, filepath.Join(root, "b")) +
+		entrySize(t, filepath.Join(root, "b", "logs"))
+	assert.Equal(t, expected, result.Size)
+}
+
+func TestWalk_WithExcludes_ReportsExcludedSize(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	createFile(t, filepath.Join(root, "a.txt"), 100)
+	createFile(t, filepath.Join(root, "b.tmp"), 200)
+	createFile(t, filepath.Join(root, "app", "node_modules", "lib", "index.js"), 300)
+
+	result, err := walker.New(walker.WithExcludes([]string{"*.tmp", "node_modules/"})).Walk(context.Background(), root)
+	require.NoError(t, err)
+
+	nodeModules := filepath.Join(root, "app", "node_modules")
+	expected := 200 + 300 + entrySize(t, nodeModules) + entrySize(t, filepath.Join(nodeModules, "lib"))
+	assert.Equal(t, expected, result.ExcludedSize)
+	assert.Equal(t, int64(1), result.Files)
+}
+
+func TestWalk_WithExcludes_CountsHardLinksOutsideExcludedDirectories(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("hard link detection is not supported on windows")
+	}
+
+	root := t.TempDir()
+	createFile(t, filepath.Join(root, "a_excluded", "f"), 1000)
+	createFile(t, filepath.Join(root, "a_excluded", "g"), 100)
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "b"), 0o755))
+	require.NoError(t, os.Link(filepath.Join(root, "a_excluded", "f"), filepath.Join(root, "b", "f")))
+	require.NoError(t, os.Link(filepath.Join(root, "a_excluded", "g"), filepath.Join(root, "a_excluded", "h")))
+
+	result, err := walker.New(walker.WithExcludes([]string{"a_excluded/"})).Walk(context.Background(), root)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(1), result.Files)
+	assert.Equal(t, 1000+entrySize(t, root)+entrySize(t, filepath.Join(root, "b")), result.Size)
+	assert.Equal(t, 1000+100+entrySize(t, filepath.Join(root, "a_excluded")), result.ExcludedSize)
+}
+
+func TestWalk_WithExcludes_NegatedPatternReincludesEntries(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	createFile(t, filepath.Join(root, "app.log"), 100)
+	createFile(t, filepath.Join(root, "important.log"), 200)
+
+	result, err := walker.New(walker.WithExcludes([]string{"*.log", "!important.log"})).Walk(context.Background(), root)
+	require.NoError(t, err)
+
+	assert.Equal(t, 200+entrySize(t, root), result.Size)
+	assert.Equal(t, int64(100), result.ExcludedSize)
+}
+
+func TestWalk_WithIncludes_OnlyCountsMatchingEntries(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	createFile(t, filepath.Join(root, "movie.mp4"), 100)
+	createFile(t, filepath.Join(root, "notes.txt"), 200)
+	createFile(t, filepath.Join(root, "videos", "raw", "clip.mov"), 300)
+	createFile(t, filepath.Join(root, "videos", "raw", "clip.tmp"), 400)
+
+	result, err := walker.New(
+		walker.WithIncludes([]string{"*.mp4", "videos/"}),
+		walker.WithExcludes([]string{"*.tmp"}),
+	).Walk(context.Background(), root)
+	require.NoError(t, err)
+
+	videos := filepath.Join(root, "videos")
+	expected := 100 + 300 + entrySize(t, root) + entrySize(t, videos) + entrySize(t, filepath.Join(videos, "raw"))
+	assert.Equal(t, expected, result.Size)
+	assert.Equal(t, int64(200+400), result.ExcludedSize)
+}
+
+func TestWalk_WithDepth_ReportsSubdirectories(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	createFile(t, filepath.Join(root, "a.txt"), 100)
+	createFile(t, filepath.Join(root, "nginx", "access.log"), 200)
+	createFile(t, filepath.Join(root, "nginx", "old", "access.log.1"), 300)
+	createFile(t, filepath.Join(root, "apt", "history.log"), 400)
+
+	result, err := walker.New(walker.WithDepth(2)).Walk(context.Background(), root)
+	require.NoError(t, err)
+
+	expected := map[string]int64{
+		"nginx":     200 + 300 + entrySize(t, filepath.Join(root, "nginx")) + entrySize(t, filepath.Join(root, "nginx", "old")),
+		"nginx/old": 300 + entrySize(t, filepath.Join(root, "nginx", "old")),
+		"apt":       400 + entrySize(t, filepath.Join(root, "apt")),
+	}
+	assert.Equal(t, expected, result.Subdirectories)
+}
+
+func TestWalk_WithoutDepth_DoesNotReportSubdirectories(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	createFile(t, filepath.Join(root, "sub", "a.txt"), 100)
+
+	result, err := walker.New().Walk(context.Background(), root)
+	require.NoError(t, err)
+
+	assert.Empty(t, result.Subdirectories)
+}
+
+func TestWalk_WithTopEntries_ReportsLargestFilesAndDirectories(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	createFile(t, filepath.Join(root, "small.txt"), 10)
+	createFile(t, filepath.Join(root, "videos", "movie.mp4"), 5000)
+	createFile(t, filepath.Join(root, "videos", "clips", "clip.mp4"), 3000)
+	createFile(t, filepath.Join(root, "logs", "app.log"), 1000)
+
+	result, err := walker.New(walker.WithTopEntries(2)).Walk(context.Background(), root)
+	require.NoError(t, err)
+
+	assert.Equal(t, []walker.Entry{
+		{Path: "videos/movie.mp4", Size: 5000},
+		{Path: "videos/clips/clip.mp4", Size: 3000},
+	}, result.LargestFiles)
+
+	clipsSize := 3000 + entrySize(t, filepath.Join(root, "videos", "clips"))
+	assert.Equal(t, []walker.Entry{
+		{Path: "videos", Size: 5000 + clipsSize + entrySize(t, filepath.Join(root, "videos"))},
+		{Path: "videos/clips", Size: clipsSize},
+	}, result.LargestDirectories)
+}
+
+func TestWalk_WithoutTopEntries_DoesNotReportLargestEntries(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	createFile(t, filepath.Join(root, "sub", "a.txt"), 100)
+
+	result, err := walker.New().Walk(context.Background(), root)
+	require.NoError(t, err)
+
+	assert.Empty(t, result.LargestFiles)
+	assert.Empty(t, result.LargestDirectories)
+}
+
+func TestWalk_WithFileTypes_ReportsSizeByType(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	createFile(t, filepath.Join(root, "movie.MP4"), 100)
+	createFile(t, filepath.Join(root, "videos", "clip.mkv"), 200)
+	createFile(t, filepath.Join(root, "backup.tar.gz"), 300)
+	createFile(t, filepath.Join(root, "app.log.gz"), 400)
+	createFile(t, filepath.Join(root, ".mp4"), 500)
+
+	types := map[string]string{".mp4": "video", ".mkv": "video", ".tar.gz": "archive", ".gz": "compressed", ".log": "logs"}
+	result, err := walker.New(walker.WithFileTypes(types)).Walk(context.Background(), root)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]int64{
+		"video":              300,
+		"archive":            300,
+		"compressed":         400,
+		"logs":               0,
+		walker.OtherFileType: 500,
+	}, result.SizeByType)
+}
+
+func TestWalk_WithoutFileTypes_DoesNotReportSizeByType(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	createFile(t, filepath.Join(root, "movie.mp4"), 100)
+
+	result, err := walker.New().Walk(context.Background(), root)
+	require.NoError(t, err)
+
+	assert.Nil(t, result.SizeByType)
+}
+
+// createFileWithAge creates a file with the given size in bytes, modified the given duration ago
+func createFileWithAge(t *testing.T, path string, size int, age time.Duration) {
+	t.Helper()
+
+	createFile(t, path, size)
+
+	modTime := time.Now().Add(-age)
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestWalk_ReportsOldestAndNewestFiles(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	createFileWithAge(t, filepath.Join(root, "old.bak"), 10, 48*time.Hour)
+	createFileWithAge(t, filepath.Join(root, "sub", "new.bak"), 10, time.Hour)
+
+	result, err := walker.New().Walk(context.Background(), root)
+	require.NoError(t, err)
+
+	assert.WithinDuration(t, time.Now().Add(-48*time.Hour), result.OldestFile, time.Minute)
+	assert.WithinDuration(t, time.Now().Add(-time.Hour), result.NewestFile, time.Minute)
+}
+
+func TestWalk_WithAgeBuckets_ReportsCumulativeSizeByAge(t *testing.T) {
+	t.Parallel()
+
+	day := 24 * time.Hour
+	root := t.TempDir()
+	createFileWithAge(t, filepath.Join(root, "today.bak"), 100, time.Hour)
+	createFileWithAge(t, filepath.Join(root, "last-month.bak"), 200, 20*day)
+	createFileWithAge(t, filepath.Join(root, "last-year.bak"), 400, 365*day)
+
+	result, err := walker.New(walker.WithAgeBuckets([]time.Duration{7 * day, 30 * day, 90 * day})).Walk(context.Background(), root)
+	require.NoError(t, err)
+
+	assert.Equal(t, []int64{100, 300, 300, 700}, result.SizeByAge)
+}
+
+func TestWalk_WithOwners_ReportsSizeByOwner(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("owners are not supported on windows")
+	}
+
+	root := t.TempDir()
+	createFile(t, filepath.Join(root, "a.txt"), 100)
+	createFile(t, filepath.Join(root, "sub", "b.txt"), 50)
+
+	result, err := walker.New(walker.WithOwners(true)).Walk(context.Background(), root)
+	require.NoError(t, err)
+
+	uid, gid := uint32(os.Getuid()), uint32(os.Getgid())
+	assert.Equal(t, map[uint32]int64{uid: result.Size}, result.SizeByUser)
+	assert.Equal(t, map[uint32]int64{gid: result.Size}, result.SizeByGroup)
+}
+
+func TestWalk_ReportsDiskUsageOfSparseFiles(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("allocated size is not supported on windows")
+	}
+
+	root := t.TempDir()
+	f, err := os.Create(filepath.Join(root, "sparse.img"))
+	require.NoError(t, err)
+	require.NoError(t, f.Truncate(100*1024*1024))
+	require.NoError(t, f.Close())
+
+	result, err := walker.New().Walk(context.Background(), root)
+	require.NoError(t, err)
+
+	assert.Greater(t, result.Size, int64(100*1024*1024))
+	assert.Less(t, result.DiskUsage, int64(1024*1024))
+}